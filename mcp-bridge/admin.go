@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var (
+	errNoBackend   = errors.New("no such backend")
+	errDisabled    = errors.New("backend is disabled")
+	errNotDisabled = errors.New("backend is not disabled")
+	errNotReady    = errors.New("backend is not ready")
+	errReplaced    = errors.New("backend was replaced during refresh")
+)
+
+// park 在后端被管理接口停用时只更新保存的配置，返回 true 表示热加载不应启动它；调用方需持有 a.reloadMu
+func (a *Aggregator) park(name, raw string, sp SrvSpec) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	sv := a.parked[name]
+	if sv == nil {
+		return false
+	}
+	a.parked[name] = &supervised{raw: raw, spec: sp, status: sv.status}
+	return true
+}
+
+// RestartBackend 用当前配置重新拉起后端；旧后端继续服务直到新后端就绪，与热加载的重启相同
+func (a *Aggregator) RestartBackend(name string) error {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	a.mu.RLock()
+	sv, parked := a.sup[name], a.parked[name] != nil
+	a.mu.RUnlock()
+	switch {
+	case parked:
+		return errDisabled
+	case sv == nil:
+		return errNoBackend
+	}
+	nsv := a.start(sv.raw, name, sv.spec)
+	a.mu.Lock()
+	nsv.status.Restarts = sv.status.Restarts + 1
+	a.mu.Unlock()
+	return nil
+}
+
+// DisableBackend 停止后端并摘除其工具；停用状态在热加载后保持，直到 EnableBackend 或 bridge 重启
+func (a *Aggregator) DisableBackend(name string) error {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	a.mu.RLock()
+	sv, parked := a.sup[name], a.parked[name] != nil
+	a.mu.RUnlock()
+	switch {
+	case parked:
+		return errDisabled
+	case sv == nil:
+		return errNoBackend
+	}
+	a.stop(name)
+	a.mu.Lock()
+	a.parked[name] = sv
+	a.mu.Unlock()
+	return nil
+}
+func (a *Aggregator) EnableBackend(name string) error {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	a.mu.Lock()
+	sv, running := a.parked[name], a.sup[name] != nil
+	delete(a.parked, name)
+	a.mu.Unlock()
+	switch {
+	case running:
+		return errNotDisabled
+	case sv == nil:
+		return errNoBackend
+	}
+	nsv := a.start(sv.raw, name, sv.spec)
+	a.mu.Lock()
+	nsv.status.Restarts = sv.status.Restarts
+	a.mu.Unlock()
+	return nil
+}
+func (a *Aggregator) backendStatus(name string) (backendStatus, bool) {
+	for _, st := range a.Status() {
+		if st.Name == name {
+			return st, true
+		}
+	}
+	return backendStatus{}, false
+}
+
+// exportedOf 返回后端导出的所有工具名（包括别名）
+func (a *Aggregator) exportedOf(name string) []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := []string{}
+	for exp, p := range a.tools {
+		if p.srv == name {
+			out = append(out, exp)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// handleBackends 实现管理接口：
+//
+//	GET  /admin/backends                 列出所有后端
+//	GET  /admin/backends/<name>          单个后端的状态和导出的工具
+//	POST /admin/backends/<name>/<action> action 为 restart、disable、enable 或 refresh
+func (s *httpServer) handleBackends(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/backends"), "/")
+	name, action, _ := strings.Cut(rest, "/")
+	if action == "" {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if name == "" {
+			writeJSON(w, map[string]any{"backends": s.agg.Status()})
+			return
+		}
+		name = sanitizeName(name)
+		st, ok := s.agg.backendStatus(name)
+		if !ok {
+			http.Error(w, errNoBackend.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, map[string]any{"backend": st, "tools": s.agg.exportedOf(name)})
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name = sanitizeName(name)
+	var err error
+	tools, code := 0, http.StatusOK
+	switch action {
+	case "restart":
+		err, code = s.agg.RestartBackend(name), http.StatusAccepted
+	case "disable":
+		err = s.agg.DisableBackend(name)
+	case "enable":
+		err, code = s.agg.EnableBackend(name), http.StatusAccepted
+	case "refresh":
+		tools, err = s.agg.refresh(name, nil, "notifications/tools/list_changed")
+	default:
+		http.Error(w, "unknown action: "+action, http.StatusNotFound)
+		return
+	}
+	switch {
+	case errors.Is(err, errNoBackend):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, errDisabled), errors.Is(err, errNotDisabled), errors.Is(err, errNotReady), errors.Is(err, errReplaced):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, secrets.scrub(err.Error()), http.StatusBadGateway)
+		return
+	}
+	log.Printf("[admin] %s %s by %s", action, name, clientFrom(r.Context()))
+	resp := map[string]any{"ok": true, "action": action}
+	if st, ok := s.agg.backendStatus(name); ok {
+		resp["backend"] = st
+	}
+	if action == "refresh" {
+		resp["tools"] = tools
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+)
+
+// AliasSpec 是 aliases 中一个后端的配置：Namespace 替换导出名前缀（默认是后端名），
+// Tools 按原始工具名配置别名和描述
+type AliasSpec struct {
+	Namespace string               `json:"namespace,omitempty"`
+	Tools     map[string]ToolAlias `json:"tools,omitempty"`
+}
+
+// ToolAlias 的 Aliases 是额外导出的完整工具名（如 "vm" 或 "vm.promql"），
+// Description 非空时覆盖该工具及其别名的描述
+type ToolAlias struct {
+	Aliases     []string `json:"aliases,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// validateAliases 在加载配置时检查命名空间和别名冲突；别名与后端真实工具的冲突只能在
+// 后端就绪后发现，那时真实工具优先
+func validateAliases(c *Config) error {
+	owner := map[string]string{}
+	for raw := range c.Servers {
+		ns := c.namespace(raw)
+		if prev, dup := owner[ns]; dup {
+			return fmt.Errorf("aliases: namespace %q is used by both %s and %s", ns, prev, raw)
+		}
+		owner[ns] = raw
+	}
+	seen := map[string]string{}
+	for raw, al := range c.Aliases {
+		if _, ok := c.Servers[raw]; !ok {
+			return fmt.Errorf("aliases.%s: no such server in mcpServers", raw)
+		}
+		if al.Namespace != "" && sanitizeName(al.Namespace) != al.Namespace {
+			return fmt.Errorf("aliases.%s: bad namespace %q (letters, digits and _ only)", raw, al.Namespace)
+		}
+		ns := c.namespace(raw)
+		for tool, ta := range al.Tools {
+			for _, name := range ta.Aliases {
+				target := ns + "." + tool
+				switch {
+				case name == "" || strings.ContainsAny(name, " \t\r\n"):
+					return fmt.Errorf("aliases.%s.tools.%s: bad alias %q", raw, tool, name)
+				case name == target:
+					return fmt.Errorf("aliases.%s.tools.%s: alias %q is the tool's own name", raw, tool, name)
+				case seen[name] != "":
+					return fmt.Errorf("aliases: %q is an alias of both %s and %s", name, seen[name], target)
+				}
+				if i := strings.Index(name, "."); i > 0 {
+					if o, ok := owner[name[:i]]; ok && o != raw {
+						return fmt.Errorf("aliases.%s.tools.%s: alias %q is in the namespace of %s", raw, tool, name, o)
+					}
+				}
+				seen[name] = target
+			}
+		}
+	}
+	return nil
+}
+
+// namespace 返回配置中后端 raw 的导出前缀
+func (c *Config) namespace(raw string) string {
+	if ns := c.Aliases[raw].Namespace; ns != "" {
+		return ns
+	}
+	return sanitizeName(raw)
+}
+
+// applyAliases 换上新的别名配置，并按它重建所有已就绪后端的导出名；导出名有变化时通知客户端
+func (a *Aggregator) applyAliases(c *Config) {
+	names := map[string]string{}
+	aliases := map[string]AliasSpec{}
+	for raw, al := range c.Aliases {
+		name := sanitizeName(raw)
+		aliases[name] = al
+		if al.Namespace != "" {
+			names[name] = al.Namespace
+		}
+	}
+	a.mu.Lock()
+	changed := len(a.catalogs) > 0 && !reflect.DeepEqual(aliases, a.aliases)
+	a.names, a.aliases = names, aliases
+	cats := make(map[string]*catalog, len(a.catalogs))
+	for name, cat := range a.catalogs {
+		cats[name] = cat
+	}
+	for name, cat := range cats {
+		a.setCatalog(name, cat)
+	}
+	a.mu.Unlock()
+	if changed {
+		a.publish("", map[string]any{"jsonrpc": "2.0", "method": "notifications/tools/list_changed"})
+	}
+}
+
+// namespace 返回后端的导出前缀；调用方需持有 a.mu
+func (a *Aggregator) namespace(name string) string {
+	if ns := a.names[name]; ns != "" {
+		return ns
+	}
+	return name
+}
+
+// backendOf 是 namespace 的反查；调用方需持有 a.mu
+func (a *Aggregator) backendOf(ns string) string {
+	for name := range a.catalogs {
+		if a.namespace(name) == ns {
+			return name
+		}
+	}
+	return ""
+}
+
+// exportTools 把后端的工具按命名空间和别名写入 a.tools；调用方需持有 a.mu 写锁
+func (a *Aggregator) exportTools(name string, tools []ToolItem) {
+	ns, al := a.namespace(name), a.aliases[name]
+	for _, t := range tools {
+		canon := ns + "." + t.Name
+		if d := al.Tools[t.Name].Description; d != "" {
+			t.Description = d
+		}
+		a.tools[canon] = toolRef{srv: name, orig: t.Name, canon: canon, item: t}
+		for _, alias := range al.Tools[t.Name].Aliases {
+			if p, ok := a.tools[alias]; ok && p.canon == alias {
+				log.Printf("[%s] alias %s collides with tool of %s -> skip", name, alias, p.srv)
+				continue
+			}
+			a.tools[alias] = toolRef{srv: name, orig: t.Name, canon: canon, item: t}
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	auditPath      = getenv("AUDIT_LOG", "/var/log/mcp-bridge/calls.jsonl")
+	auditMaxSize   = int64(getenvInt("AUDIT_MAX_SIZE_MB", 64)) << 20
+	auditMaxAge    = getenvDur("AUDIT_MAX_AGE", 24*time.Hour)
+	auditRetention = getenvDur("AUDIT_RETENTION", 7*24*time.Hour)
+)
+
+// auditRecord 是审计日志中的一行，每次 tools/call 一条
+type auditRecord struct {
+	TS          time.Time      `json:"ts"`
+	Client      string         `json:"client,omitempty"`
+	Session     string         `json:"session,omitempty"`
+	Tool        string         `json:"tool"`
+	Backend     string         `json:"backend,omitempty"`
+	Original    string         `json:"original,omitempty"`
+	Args        map[string]any `json:"args,omitempty"`
+	DurationMs  int64          `json:"durationMs"`
+	ResultBytes int            `json:"resultBytes"`
+	Cached      bool           `json:"cached,omitempty"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// auditLog 追加写 JSONL，按大小和时间轮转，超过保留期的旧文件会被删除
+type auditLog struct {
+	mu     sync.Mutex
+	path   string
+	f      *os.File
+	size   int64
+	opened time.Time
+}
+
+func openAuditLog(p string) (*auditLog, error) {
+	if p == "" || p == "off" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return nil, err
+	}
+	al := &auditLog{path: p}
+	if err := al.open(); err != nil {
+		return nil, err
+	}
+	return al, nil
+}
+func (al *auditLog) open() error {
+	f, err := os.OpenFile(al.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	al.f, al.size, al.opened = f, st.Size(), st.ModTime()
+	if st.Size() == 0 {
+		al.opened = time.Now()
+	}
+	return nil
+}
+func (al *auditLog) write(rec auditRecord) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	b = append(b, '\n')
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if al.size > 0 && (al.size+int64(len(b)) > auditMaxSize || time.Since(al.opened) > auditMaxAge) {
+		al.rotate()
+	}
+	if al.f == nil {
+		return
+	}
+	n, err := al.f.Write(b)
+	al.size += int64(n)
+	if err != nil {
+		log.Printf("[audit] write: %v", err)
+	}
+}
+
+// rotate 调用方需持有 al.mu
+func (al *auditLog) rotate() {
+	_ = al.f.Close()
+	al.f = nil
+	ext := filepath.Ext(al.path)
+	rotated := strings.TrimSuffix(al.path, ext) + "-" + time.Now().UTC().Format("20060102T150405.000") + ext
+	if err := os.Rename(al.path, rotated); err != nil {
+		log.Printf("[audit] rotate: %v", err)
+	}
+	if err := al.open(); err != nil {
+		log.Printf("[audit] reopen: %v", err)
+	}
+	for _, old := range al.rotated() {
+		if st, err := os.Stat(old); err == nil && time.Since(st.ModTime()) > auditRetention {
+			_ = os.Remove(old)
+		}
+	}
+}
+func (al *auditLog) rotated() []string {
+	ext := filepath.Ext(al.path)
+	files, _ := filepath.Glob(strings.TrimSuffix(al.path, ext) + "-*" + ext)
+	sort.Strings(files)
+	return files
+}
+func (al *auditLog) Close() {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if al.f != nil {
+		_ = al.f.Close()
+		al.f = nil
+	}
+}
+
+type auditQuery struct {
+	tool, session, client string
+	since, until          time.Time
+	limit                 int
+}
+
+// query 按时间顺序扫描轮转文件和当前文件，返回最近 limit 条匹配记录
+func (al *auditLog) query(q auditQuery) ([]auditRecord, error) {
+	al.mu.Lock()
+	files := append(al.rotated(), al.path)
+	al.mu.Unlock()
+	var out []auditRecord
+	for _, p := range files {
+		f, err := os.Open(p)
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for sc.Scan() {
+			var rec auditRecord
+			if json.Unmarshal(sc.Bytes(), &rec) != nil {
+				continue
+			}
+			if !q.since.IsZero() && rec.TS.Before(q.since) || !q.until.IsZero() && rec.TS.After(q.until) {
+				continue
+			}
+			if q.session != "" && rec.Session != q.session || q.client != "" && rec.Client != q.client {
+				continue
+			}
+			if q.tool != "" {
+				if ok, _ := path.Match(q.tool, rec.Tool); !ok {
+					continue
+				}
+			}
+			out = append(out, rec)
+			if len(out) > q.limit {
+				out = out[1:]
+			}
+		}
+		f.Close()
+	}
+	return out, nil
+}
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// handleCalls 实现 GET /admin/calls?tool=&session=&client=&since=&until=&limit=
+// since/until 可以是 RFC3339、unix 秒或相对时长（如 1h 表示一小时前）
+func (s *httpServer) handleCalls(w http.ResponseWriter, r *http.Request) {
+	if s.agg.audit == nil {
+		http.Error(w, "audit log disabled", http.StatusNotFound)
+		return
+	}
+	qv := r.URL.Query()
+	q := auditQuery{tool: qv.Get("tool"), session: qv.Get("session"), client: qv.Get("client"), limit: 100}
+	var err error
+	if q.since, err = parseTimeParam(qv.Get("since")); err != nil {
+		http.Error(w, "bad since: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.until, err = parseTimeParam(qv.Get("until")); err != nil {
+		http.Error(w, "bad until: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if v := qv.Get("limit"); v != "" {
+		if q.limit, err = strconv.Atoi(v); err != nil || q.limit <= 0 {
+			http.Error(w, "bad limit", http.StatusBadRequest)
+			return
+		}
+	}
+	recs, err := s.agg.audit.query(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]any{"calls": recs, "count": len(recs)})
+}
+
+// redactValue 递归地把键名匹配 sensitiveKey 的值替换为 ***
+func redactValue(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, vv := range x {
+			if sensitiveKey.MatchString(k) {
+				out[k] = "***"
+			} else {
+				out[k] = redactValue(vv)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(x))
+		for i, vv := range x {
+			out[i] = redactValue(vv)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// recordCall 写一条审计记录；audit 未开启时什么也不做
+func (a *Aggregator) recordCall(ctx context.Context, tool, backend, orig string, args map[string]any, start time.Time, res map[string]any, cached bool, err error) {
+	if a.audit == nil {
+		return
+	}
+	rec := auditRecord{
+		TS:         start.UTC(),
+		Client:     clientFrom(ctx),
+		Tool:       tool,
+		Backend:    backend,
+		Original:   orig,
+		DurationMs: time.Since(start).Milliseconds(),
+		Cached:     cached,
+	}
+	if se := sessionFrom(ctx); se != nil {
+		rec.Session = se.ID
+	}
+	if args != nil {
+		rec.Args, _ = redactValue(args).(map[string]any)
+	}
+	if res != nil {
+		b, _ := json.Marshal(res)
+		rec.ResultBytes = len(b)
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	} else if res["isError"] == true {
+		rec.Error = "tool returned isError"
+	}
+	a.audit.write(rec)
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+type AuthSpec struct {
+	// AllowLocalhost 为 true 时来自回环地址的请求免认证（身份记为 "localhost"）
+	AllowLocalhost bool      `json:"allowLocalhost,omitempty"`
+	HMACSecret     string    `json:"hmacSecret,omitempty"`
+	Keys           []KeySpec `json:"keys,omitempty"`
+	TLS            *TLSSpec  `json:"tls,omitempty"`
+}
+
+// KeySpec 是一个静态 API key；推荐只存 Hash = hex(HMAC-SHA256(hmacSecret, key))
+// Admin 为 true 的 key 可以访问 /admin/ 下的接口
+type KeySpec struct {
+	ID    string `json:"id"`
+	Key   string `json:"key,omitempty"`
+	Hash  string `json:"hash,omitempty"`
+	Admin bool   `json:"admin,omitempty"`
+}
+type TLSSpec struct {
+	CertFile          string   `json:"certFile"`
+	KeyFile           string   `json:"keyFile"`
+	ClientCAFile      string   `json:"clientCAFile,omitempty"`
+	RequireClientCert bool     `json:"requireClientCert,omitempty"`
+	AllowedCNs        []string `json:"allowedCNs,omitempty"`
+	AdminCNs          []string `json:"adminCNs,omitempty"`
+}
+
+type storedKey struct {
+	id   string
+	hash []byte
+}
+type authenticator struct {
+	allowLocalhost bool
+	secret         []byte
+	keys           []storedKey
+	mtls           bool
+	allowedCNs     map[string]bool
+	admins         map[string]bool
+}
+
+// newAuthenticator 未配置 auth 时只放行本机请求
+func newAuthenticator(a *AuthSpec) (*authenticator, error) {
+	if a == nil {
+		return &authenticator{allowLocalhost: true}, nil
+	}
+	au := &authenticator{allowLocalhost: a.AllowLocalhost, admins: map[string]bool{}}
+	secret := a.HMACSecret
+	if secret == "" {
+		secret = os.Getenv("AUTH_HMAC_SECRET")
+	}
+	if secret != "" {
+		au.secret = []byte(secret)
+	} else {
+		// 只有明文 key 时用进程内随机密钥，内存里同样只保留哈希
+		au.secret = make([]byte, 32)
+		_, _ = rand.Read(au.secret)
+	}
+	seen := map[string]bool{}
+	for _, k := range a.Keys {
+		// key id 与 "localhost"、"cert:<CN>" 身份共用策略和管理员的命名空间，不能冲突
+		switch {
+		case k.ID == "":
+			return nil, errors.New("auth: key without id")
+		case k.ID == "localhost" || strings.HasPrefix(k.ID, "cert:"):
+			return nil, fmt.Errorf("auth: key id %q is reserved", k.ID)
+		case seen[k.ID]:
+			return nil, fmt.Errorf("auth: duplicate key id %s", k.ID)
+		}
+		seen[k.ID] = true
+		switch {
+		case k.Hash != "":
+			if secret == "" {
+				return nil, fmt.Errorf("auth: key %s is hashed but hmacSecret is not set", k.ID)
+			}
+			h, err := hex.DecodeString(strings.TrimSpace(k.Hash))
+			if err != nil || len(h) != sha256.Size {
+				return nil, fmt.Errorf("auth: key %s: bad hash", k.ID)
+			}
+			au.keys = append(au.keys, storedKey{id: k.ID, hash: h})
+		case k.Key != "":
+			au.keys = append(au.keys, storedKey{id: k.ID, hash: au.mac(k.Key)})
+		default:
+			return nil, fmt.Errorf("auth: key %s has neither key nor hash", k.ID)
+		}
+		if k.Admin {
+			au.admins[k.ID] = true
+		}
+	}
+	if t := a.TLS; t != nil && t.ClientCAFile != "" {
+		au.mtls = true
+		if len(t.AllowedCNs) > 0 {
+			au.allowedCNs = map[string]bool{}
+			for _, cn := range t.AllowedCNs {
+				au.allowedCNs[cn] = true
+			}
+		}
+		for _, cn := range t.AdminCNs {
+			au.admins["cert:"+cn] = true
+		}
+	}
+	return au, nil
+}
+func (au *authenticator) mac(key string) []byte {
+	m := hmac.New(sha256.New, au.secret)
+	m.Write([]byte(key))
+	return m.Sum(nil)
+}
+func (au *authenticator) describe() string {
+	var parts []string
+	if au.allowLocalhost {
+		parts = append(parts, "localhost")
+	}
+	if len(au.keys) > 0 {
+		parts = append(parts, fmt.Sprintf("%d api key(s)", len(au.keys)))
+	}
+	if au.mtls {
+		parts = append(parts, "mtls")
+	}
+	if len(parts) == 0 {
+		return "deny all"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// identify 返回请求方身份；ok=false 表示未通过认证
+func (au *authenticator) identify(r *http.Request) (string, bool) {
+	if au.mtls && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		if au.allowedCNs == nil || au.allowedCNs[cn] {
+			return "cert:" + cn, true
+		}
+	}
+	if key := presentedKey(r); key != "" && len(au.keys) > 0 {
+		h := au.mac(key)
+		for _, k := range au.keys {
+			if hmac.Equal(h, k.hash) {
+				return k.id, true
+			}
+		}
+		return "", false
+	}
+	if au.allowLocalhost && isLoopback(r.RemoteAddr) {
+		return "localhost", true
+	}
+	return "", false
+}
+func presentedKey(r *http.Request) string {
+	if v := r.Header.Get("Authorization"); len(v) > 7 && strings.EqualFold(v[:7], "bearer ") {
+		return strings.TrimSpace(v[7:])
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+func isLoopback(remote string) bool {
+	host := remote
+	if h, _, err := net.SplitHostPort(remote); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// requireAuth 认证失败返回 401，成功后把身份放进请求上下文
+func (s *httpServer) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := s.auth.Load().identify(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp-bridge"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r.WithContext(withClient(r.Context(), id)))
+	}
+}
+
+// isAdmin 本机请求（allowLocalhost 时）和标记了 admin 的 key / 证书可以访问管理接口
+func (au *authenticator) isAdmin(id string) bool {
+	return id == "localhost" || au.admins[id]
+}
+
+// requireAdmin 在 requireAuth 的基础上要求管理员身份，否则 403
+func (s *httpServer) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return s.requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if !s.auth.Load().isAdmin(clientFrom(r.Context())) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// validateAuth 检查 auth 能否构造出认证器和 TLS 配置
+func validateAuth(a *AuthSpec) error {
+	if _, err := newAuthenticator(a); err != nil {
+		return err
+	}
+	_, err := serverTLSConfig(a)
+	return err
+}
+
+// serverTLSConfig 配置了证书时启用 HTTPS；配置 clientCAFile 时校验客户端证书（mTLS）
+func serverTLSConfig(a *AuthSpec) (*tls.Config, error) {
+	if a == nil || a.TLS == nil || a.TLS.CertFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(a.TLS.CertFile, a.TLS.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if a.TLS.ClientCAFile != "" {
+		pem, err := os.ReadFile(a.TLS.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls: no certificates in %s", a.TLS.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if a.TLS.RequireClientCert {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return cfg, nil
+}
+
+type clientKey struct{}
+
+func withClient(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, clientKey{}, id)
+}
+func clientFrom(ctx context.Context) string {
+	id, _ := ctx.Value(clientKey{}).(string)
+	return id
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestAuthenticatorKeyIDs(t *testing.T) {
+	cases := []struct {
+		name string
+		keys []KeySpec
+		ok   bool
+	}{
+		{"distinct ids", []KeySpec{{ID: "qproxy", Key: "k-one"}, {ID: "ops", Key: "k-two", Admin: true}}, true},
+		{"missing id", []KeySpec{{Key: "k-one"}}, false},
+		{"localhost reserved", []KeySpec{{ID: "localhost", Key: "k-one"}}, false},
+		{"cert prefix reserved", []KeySpec{{ID: "cert:ops", Key: "k-one"}}, false},
+		{"duplicate id", []KeySpec{{ID: "ops", Key: "k-one"}, {ID: "ops", Key: "k-two"}}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newAuthenticator(&AuthSpec{Keys: tc.keys})
+			if (err == nil) != tc.ok {
+				t.Fatalf("newAuthenticator = %v, want ok=%v", err, tc.ok)
+			}
+		})
+	}
+}
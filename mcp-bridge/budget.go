@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// BudgetSpec 限制某个工具返回给客户端的结果大小：MaxBytes 是文本内容的字节上限，
+// MaxItems 是序列、命中或数组元素的条数上限；Hint 覆盖默认的缩小查询提示
+type BudgetSpec struct {
+	MaxBytes int    `json:"maxBytes,omitempty"`
+	MaxItems int    `json:"maxItems,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+}
+
+// truncatedMeta 是结果 _meta 中标记被截断的键，同样的摘要也写进 JSON 文本的 "_truncated" 字段，
+// 因为不是所有客户端都会把 _meta 交给模型
+const truncatedMeta = "truncated"
+
+func validateBudgets(servers map[string]SrvSpec) error {
+	for name, sp := range servers {
+		for tool, b := range sp.OutputBudget {
+			if b.MaxBytes < 0 || b.MaxItems < 0 || b.MaxBytes == 0 && b.MaxItems == 0 {
+				return fmt.Errorf("mcpServers.%s.outputBudget.%s: maxBytes or maxItems must be positive", name, tool)
+			}
+		}
+	}
+	return nil
+}
+
+// budgetFor 与缓存一样先找原始工具名，再找 "*"
+func (a *Aggregator) budgetFor(srv, tool string) *BudgetSpec {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	sv := a.sup[srv]
+	if sv == nil {
+		return nil
+	}
+	if b, ok := sv.spec.OutputBudget[tool]; ok {
+		return &b
+	}
+	if b, ok := sv.spec.OutputBudget["*"]; ok {
+		return &b
+	}
+	return nil
+}
+
+// applyBudget 按预算截断结果中的文本内容；res 可能来自缓存，只复制不修改
+func (a *Aggregator) applyBudget(srv, tool string, res map[string]any) map[string]any {
+	b := a.budgetFor(srv, tool)
+	content, _ := res["content"].([]any)
+	if b == nil || len(content) == 0 {
+		return res
+	}
+	var texts []int
+	for i, c := range content {
+		if m, ok := c.(map[string]any); ok && m["type"] == "text" {
+			texts = append(texts, i)
+		}
+	}
+	if len(texts) == 0 {
+		return res
+	}
+	share := b.MaxBytes / len(texts)
+	out := append([]any(nil), content...)
+	var marker map[string]any
+	orig, kept := 0, 0
+	for _, i := range texts {
+		m := content[i].(map[string]any)
+		text, _ := m["text"].(string)
+		cut, sum := truncateText(text, share, b.MaxItems, b.Hint)
+		orig += len(text)
+		kept += len(cut)
+		if sum == nil {
+			continue
+		}
+		out[i] = withField(m, "text", cut)
+		if marker == nil {
+			marker = sum
+		}
+	}
+	if marker == nil {
+		return res
+	}
+	bridgeMetrics.resultTruncated(srv, tool)
+	res = withField(res, "content", out)
+	// structuredContent 是同一份数据，截断后不再一致，直接去掉
+	delete(res, "structuredContent")
+	meta, _ := res["_meta"].(map[string]any)
+	res["_meta"] = withField(meta, truncatedMeta, withField(withField(marker, "originalBytes", orig), "returnedBytes", kept))
+	return res
+}
+
+// shape 是识别出的结果结构：items 是可以按条截断的部分，build 用前 n 条重新组装文档
+type shape struct {
+	kind  string
+	items []any
+	build func(items []any, summary map[string]any) any
+	// omitted 统计被丢掉的条目里还有多少子项（如 Prometheus 的样本数）
+	omitted func(dropped []any) map[string]any
+}
+
+// truncateText 超出预算时返回截断后的文本和摘要；未超出时原样返回，摘要为 nil
+func truncateText(text string, maxBytes, maxItems int, hint string) (string, map[string]any) {
+	over := maxBytes > 0 && len(text) > maxBytes
+	dec := json.NewDecoder(bytes.NewReader([]byte(text)))
+	dec.UseNumber()
+	var doc any
+	var sh *shape
+	if dec.Decode(&doc) == nil {
+		if _, err := dec.Token(); err == io.EOF {
+			sh = detectShape(doc)
+		}
+	}
+	if sh == nil {
+		if !over {
+			return text, nil
+		}
+		return cutText(text, maxBytes, hint)
+	}
+	total := len(sh.items)
+	if !over && (maxItems == 0 || total <= maxItems) {
+		return text, nil
+	}
+	n := total
+	if maxItems > 0 && n > maxItems {
+		n = maxItems
+	}
+	build := func(n int) (string, map[string]any, bool) {
+		summary := map[string]any{"kind": sh.kind, "total": total, "returned": n, "omitted": total - n}
+		if sh.omitted != nil {
+			for k, v := range sh.omitted(sh.items[n:]) {
+				summary[k] = v
+			}
+		}
+		summary["hint"] = budgetHint(sh.kind, hint)
+		out, err := marshalCompact(sh.build(sh.items[:n], summary))
+		return out, summary, err == nil && (maxBytes == 0 || len(out) <= maxBytes)
+	}
+	if out, summary, ok := build(n); ok {
+		return out, summary
+	}
+	// 二分找出预算内能保留的最多条数
+	lo, hi := -1, n
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if _, _, ok := build(mid); ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	if lo < 0 {
+		// 去掉所有条目仍然超出（比如 Elasticsearch 的 aggregations 很大），退回按字节截断
+		return cutText(text, maxBytes, hint)
+	}
+	out, summary, _ := build(lo)
+	return out, summary
+}
+func detectShape(doc any) *shape {
+	switch d := doc.(type) {
+	case []any:
+		return &shape{kind: "array", items: d, build: func(items []any, summary map[string]any) any {
+			return map[string]any{"items": items, "_truncated": summary}
+		}}
+	case map[string]any:
+		if data, ok := d["data"].(map[string]any); ok {
+			if result, ok := data["result"].([]any); ok && data["resultType"] != nil {
+				return prometheusShape(d, data, result)
+			}
+		}
+		if hits, ok := d["hits"].(map[string]any); ok {
+			if list, ok := hits["hits"].([]any); ok {
+				sh := &shape{kind: "elasticsearch", items: list, build: func(items []any, summary map[string]any) any {
+					return withField(withField(d, "hits", withField(hits, "hits", items)), "_truncated", summary)
+				}}
+				if t := esTotal(hits["total"]); t > len(list) {
+					sh.omitted = func([]any) map[string]any { return map[string]any{"matched": t} }
+				}
+				return sh
+			}
+		}
+	}
+	return nil
+}
+
+// prometheusShape 按峰值从大到小保留序列，RCA 时最突出的序列通常最有用
+func prometheusShape(d, data map[string]any, result []any) *shape {
+	series := append([]any(nil), result...)
+	sort.SliceStable(series, func(i, j int) bool { return seriesPeak(series[i]) > seriesPeak(series[j]) })
+	return &shape{
+		kind:  "prometheus",
+		items: series,
+		build: func(items []any, summary map[string]any) any {
+			return withField(withField(d, "data", withField(data, "result", items)), "_truncated", summary)
+		},
+		omitted: func(dropped []any) map[string]any {
+			samples := 0
+			for _, s := range dropped {
+				if m, ok := s.(map[string]any); ok {
+					if v, ok := m["values"].([]any); ok {
+						samples += len(v)
+					} else if m["value"] != nil {
+						samples++
+					}
+				}
+			}
+			return map[string]any{"omittedSamples": samples}
+		},
+	}
+}
+func seriesPeak(s any) float64 {
+	peak := math.Inf(-1)
+	m, _ := s.(map[string]any)
+	points, _ := m["values"].([]any)
+	if v, ok := m["value"]; ok {
+		points = append(points, v)
+	}
+	for _, p := range points {
+		pair, _ := p.([]any)
+		if len(pair) != 2 {
+			continue
+		}
+		str, _ := pair[1].(string)
+		if f, err := strconv.ParseFloat(str, 64); err == nil && !math.IsNaN(f) && f > peak {
+			peak = f
+		}
+	}
+	return peak
+}
+
+// esTotal 兼容 hits.total 的两种写法：数字（6.x）和 {"value": N}（7.x 以后）
+func esTotal(v any) int {
+	if m, ok := v.(map[string]any); ok {
+		v = m["value"]
+	}
+	if n, ok := v.(json.Number); ok {
+		i, _ := n.Int64()
+		return int(i)
+	}
+	return 0
+}
+func budgetHint(kind, hint string) string {
+	if hint != "" {
+		return hint
+	}
+	switch kind {
+	case "prometheus":
+		return "Only the series with the highest peak values are shown. Narrow the query: add label matchers, aggregate with sum/topk by (...), shorten the time range or increase step."
+	case "elasticsearch":
+		return "Only the first hits are shown. Narrow the search: add filters or a shorter time range, lower size, or use aggregations and _source includes."
+	case "array":
+		return "Only the first items are shown. Narrow the request to get fewer results."
+	}
+	return "The output was cut. Narrow the request to get less data."
+}
+
+// cutText 在 UTF-8 字符边界处按字节截断，并在末尾说明截断了多少
+func cutText(text string, maxBytes int, hint string) (string, map[string]any) {
+	if maxBytes <= 0 || len(text) <= maxBytes {
+		return text, nil
+	}
+	hint = budgetHint("text", hint)
+	note := func(n int) string {
+		return fmt.Sprintf("\n\n[truncated: %d of %d bytes omitted. %s]", len(text)-n, len(text), hint)
+	}
+	// 说明文字也算在预算内
+	n := maxBytes - len(note(0))
+	if n < 0 {
+		n = 0
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	summary := map[string]any{"kind": "text", "total": len(text), "returned": n, "omitted": len(text) - n, "hint": hint}
+	return text[:n] + note(n), summary
+}
+func marshalCompact(v any) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return string(bytes.TrimRight(buf.Bytes(), "\n")), nil
+}
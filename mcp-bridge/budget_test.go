@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// promDoc 生成 n 条序列的 Prometheus range 查询结果，第 i 条序列的峰值为 i
+func promDoc(n int) string {
+	result := make([]any, n)
+	for i := range result {
+		result[i] = map[string]any{
+			"metric": map[string]any{"instance": fmt.Sprintf("host-%d", i)},
+			"values": []any{[]any{1700000000, "0"}, []any{1700000060, fmt.Sprint(i)}},
+		}
+	}
+	b, _ := json.Marshal(map[string]any{"status": "success", "data": map[string]any{"resultType": "matrix", "result": result}})
+	return string(b)
+}
+func esDoc(n, total int, aggs string) string {
+	hits := make([]any, n)
+	for i := range hits {
+		hits[i] = map[string]any{"_id": fmt.Sprint(i), "_source": map[string]any{"msg": strings.Repeat("x", 40)}}
+	}
+	doc := map[string]any{"took": 3, "hits": map[string]any{"total": map[string]any{"value": total}, "hits": hits}}
+	if aggs != "" {
+		doc["aggregations"] = map[string]any{"big": aggs}
+	}
+	b, _ := json.Marshal(doc)
+	return string(b)
+}
+func arrayDoc(n int) string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = "item-xxxxx"
+	}
+	b, _ := json.Marshal(items)
+	return string(b)
+}
+
+func TestTruncateText(t *testing.T) {
+	cases := []struct {
+		name     string
+		text     string
+		maxBytes int
+		maxItems int
+		kind     string // 期望的摘要类型，为空表示不截断
+		returned int    // -1 表示不检查
+		extra    map[string]any
+	}{
+		{"under budget", promDoc(3), 1 << 20, 10, "", -1, nil},
+		{"prometheus max items", promDoc(10), 0, 3, "prometheus", 3, map[string]any{"total": 10, "omitted": 7, "omittedSamples": 14}},
+		{"prometheus max bytes", promDoc(50), 2000, 0, "prometheus", -1, nil},
+		{"elasticsearch max items", esDoc(20, 5000, ""), 0, 5, "elasticsearch", 5, map[string]any{"total": 20, "matched": 5000}},
+		{"elasticsearch falls back to text", esDoc(5, 5, strings.Repeat("a", 5000)), 1000, 0, "text", -1, nil},
+		{"array max items", arrayDoc(30), 0, 4, "array", 4, nil},
+		{"plain text", strings.Repeat("日志行\n", 2000), 500, 0, "text", -1, nil},
+		{"plain text under budget", "short", 500, 0, "", -1, nil},
+		{"not json with max items only", "a\nb\nc", 0, 1, "", -1, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, sum := truncateText(tc.text, tc.maxBytes, tc.maxItems, "")
+			if tc.kind == "" {
+				if sum != nil || out != tc.text {
+					t.Fatalf("text was changed: summary %v", sum)
+				}
+				return
+			}
+			if sum == nil {
+				t.Fatal("text was not truncated")
+			}
+			if sum["kind"] != tc.kind {
+				t.Fatalf("kind %v, want %s", sum["kind"], tc.kind)
+			}
+			if tc.maxBytes > 0 && len(out) > tc.maxBytes {
+				t.Fatalf("output is %d bytes, budget %d", len(out), tc.maxBytes)
+			}
+			if !utf8.ValidString(out) {
+				t.Fatal("output is not valid UTF-8")
+			}
+			if tc.returned >= 0 && sum["returned"] != tc.returned {
+				t.Fatalf("returned %v, want %d", sum["returned"], tc.returned)
+			}
+			for k, v := range tc.extra {
+				if sum[k] != v {
+					t.Fatalf("summary %s = %v, want %v", k, sum[k], v)
+				}
+			}
+			if tc.kind != "text" {
+				var doc any
+				if err := json.Unmarshal([]byte(out), &doc); err != nil {
+					t.Fatalf("truncated %s output is not JSON: %v", tc.kind, err)
+				}
+			}
+		})
+	}
+}
+
+// TestTruncatePrometheusKeepsPeaks 检查保留的是峰值最高的序列
+func TestTruncatePrometheusKeepsPeaks(t *testing.T) {
+	out, _ := truncateText(promDoc(10), 0, 3, "")
+	var doc struct {
+		Data struct {
+			Result []struct {
+				Metric map[string]string `json:"metric"`
+			} `json:"result"`
+		} `json:"data"`
+		Truncated map[string]any `json:"_truncated"`
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range doc.Data.Result {
+		got = append(got, r.Metric["instance"])
+	}
+	if strings.Join(got, ",") != "host-9,host-8,host-7" {
+		t.Fatalf("kept series %v, want the three highest peaks", got)
+	}
+	if doc.Truncated["hint"] == nil {
+		t.Fatal("document has no _truncated hint")
+	}
+}
+
+// TestTruncateBinarySearch 检查按字节预算保留的是能放下的最多条数，而不是粗略减半
+func TestTruncateBinarySearch(t *testing.T) {
+	text := arrayDoc(200)
+	item := len(`"item-xxxxx",`)
+	for _, budget := range []int{600, 1000, 1500, 2222} {
+		out, sum := truncateText(text, budget, 0, "")
+		if sum == nil || len(out) > budget {
+			t.Fatalf("budget %d: output %d bytes, summary %v", budget, len(out), sum)
+		}
+		// 再多放一条就会超出预算
+		if budget-len(out) >= item {
+			t.Fatalf("budget %d: output %d bytes, room for another item", budget, len(out))
+		}
+	}
+}
+func TestCutTextRuneBoundary(t *testing.T) {
+	text := strings.Repeat("错", 1000)
+	for budget := 200; budget < 210; budget++ {
+		out, sum := cutText(text, budget, "")
+		if len(out) > budget || !utf8.ValidString(out) || sum == nil {
+			t.Fatalf("budget %d: %d bytes, valid=%v", budget, len(out), utf8.ValidString(out))
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CacheSpec 配置某个工具的结果缓存；只应该给幂等的只读工具打开
+type CacheSpec struct {
+	TTL        string `json:"ttl"`
+	MaxEntries int    `json:"maxEntries,omitempty"`
+}
+
+const defaultCacheEntries = 256
+
+// noCacheMeta 是 tools/call 的 params._meta 中用来绕过缓存的键
+const noCacheMeta = "noCache"
+
+func (c CacheSpec) ttl() (time.Duration, error) {
+	d, err := time.ParseDuration(c.TTL)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("bad ttl %q", c.TTL)
+	}
+	return d, nil
+}
+func validateCache(servers map[string]SrvSpec) error {
+	for name, sp := range servers {
+		for tool, c := range sp.Cache {
+			if _, err := c.ttl(); err != nil {
+				return fmt.Errorf("mcpServers.%s.cache.%s: %w", name, tool, err)
+			}
+			if c.MaxEntries < 0 {
+				return fmt.Errorf("mcpServers.%s.cache.%s: maxEntries must not be negative", name, tool)
+			}
+		}
+	}
+	return nil
+}
+
+type cacheEntry struct {
+	key     string
+	res     map[string]any
+	expires time.Time
+}
+type inflightCall struct {
+	done chan struct{}
+	res  map[string]any
+	err  error
+}
+
+// toolCache 是一个工具（或 "*" 下一个后端所有工具）的 LRU + TTL 缓存，同时合并相同参数的并发调用
+type toolCache struct {
+	ttl      time.Duration
+	max      int
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List
+	inflight map[string]*inflightCall
+}
+
+func newToolCache(c CacheSpec) *toolCache {
+	ttl, _ := c.ttl()
+	max := c.MaxEntries
+	if max == 0 {
+		max = defaultCacheEntries
+	}
+	return &toolCache{ttl: ttl, max: max, entries: map[string]*list.Element{}, lru: list.New(), inflight: map[string]*inflightCall{}}
+}
+
+// call 命中缓存时直接返回；否则同 key 的并发调用只有一个真正发给后端
+func (tc *toolCache) call(ctx context.Context, key string, bypass bool, fn func() (map[string]any, error)) (map[string]any, bool, error) {
+	tc.mu.Lock()
+	if !bypass {
+		if el, ok := tc.entries[key]; ok {
+			e := el.Value.(*cacheEntry)
+			if time.Now().Before(e.expires) {
+				tc.lru.MoveToFront(el)
+				tc.mu.Unlock()
+				return e.res, true, nil
+			}
+			tc.lru.Remove(el)
+			delete(tc.entries, key)
+		}
+		if c, ok := tc.inflight[key]; ok {
+			tc.mu.Unlock()
+			select {
+			case <-c.done:
+			case <-ctx.Done():
+				return nil, false, ctx.Err()
+			}
+			// 发起方自己被取消时，等待者不应跟着失败
+			if errors.Is(c.err, context.Canceled) || errors.Is(c.err, context.DeadlineExceeded) {
+				return tc.call(ctx, key, bypass, fn)
+			}
+			return c.res, true, c.err
+		}
+	}
+	c := &inflightCall{done: make(chan struct{})}
+	if !bypass {
+		tc.inflight[key] = c
+	}
+	tc.mu.Unlock()
+	c.res, c.err = fn()
+	tc.mu.Lock()
+	if tc.inflight[key] == c {
+		delete(tc.inflight, key)
+	}
+	if c.err == nil && c.res["isError"] != true {
+		tc.store(key, c.res)
+	}
+	tc.mu.Unlock()
+	close(c.done)
+	return c.res, false, c.err
+}
+
+// store 调用方需持有 tc.mu
+func (tc *toolCache) store(key string, res map[string]any) {
+	e := &cacheEntry{key: key, res: res, expires: time.Now().Add(tc.ttl)}
+	if el, ok := tc.entries[key]; ok {
+		el.Value = e
+		tc.lru.MoveToFront(el)
+		return
+	}
+	tc.entries[key] = tc.lru.PushFront(e)
+	for tc.lru.Len() > tc.max {
+		old := tc.lru.Back()
+		tc.lru.Remove(old)
+		delete(tc.entries, old.Value.(*cacheEntry).key)
+	}
+}
+
+// cacheKey 用工具名加规范化后的参数做 key；"*" 的缓存由后端的所有工具共用，必须带上工具名。
+// encoding/json 对 map 按键排序输出
+func cacheKey(tool string, args map[string]any) (string, bool) {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return "", false
+	}
+	return tool + "\x00" + string(b), true
+}
+
+// setCaches 按后端配置重建该后端的缓存；spec 为 nil 时清空
+func (a *Aggregator) setCaches(name string, spec map[string]CacheSpec) {
+	a.cacheMu.Lock()
+	defer a.cacheMu.Unlock()
+	for k := range a.caches {
+		if k.srv == name {
+			delete(a.caches, k)
+		}
+	}
+	for tool, c := range spec {
+		a.caches[toolKey{name, tool}] = newToolCache(c)
+	}
+}
+
+// cacheFor 先找工具名精确匹配的配置，再找 "*"
+func (a *Aggregator) cacheFor(srv, tool string) *toolCache {
+	a.cacheMu.Lock()
+	defer a.cacheMu.Unlock()
+	if tc := a.caches[toolKey{srv, tool}]; tc != nil {
+		return tc
+	}
+	return a.caches[toolKey{srv, "*"}]
+}
+
+type toolKey struct{ srv, tool string }
+
+type noCacheCtxKey struct{}
+
+func withNoCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheCtxKey{}, true)
+}
+func noCacheFrom(ctx context.Context) bool {
+	v, _ := ctx.Value(noCacheCtxKey{}).(bool)
+	return v
+}
+
+// callCached 在 CallTool 前面加上缓存；未配置缓存的工具直接透传
+func (a *Aggregator) callCached(ctx context.Context, bk Backend, orig string, args map[string]any) (map[string]any, bool, error) {
+	tc := a.cacheFor(bk.Name(), orig)
+	key, ok := cacheKey(orig, args)
+	if tc == nil || !ok {
+		res, err := a.callBackend(ctx, bk, orig, args)
+		return res, false, err
+	}
+	return tc.call(ctx, key, noCacheFrom(ctx), func() (map[string]any, error) { return a.callBackend(ctx, bk, orig, args) })
+}
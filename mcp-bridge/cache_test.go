@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// fakeBackend 记录每个工具被调用的次数，结果里带上工具名
+type fakeBackend struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (f *fakeBackend) Name() string                                  { return "es" }
+func (f *fakeBackend) Initialize(context.Context) error              { return nil }
+func (f *fakeBackend) Capabilities() map[string]any                  { return nil }
+func (f *fakeBackend) ListTools(context.Context) ([]ToolItem, error) { return nil, nil }
+func (f *fakeBackend) Request(context.Context, string, map[string]any) (map[string]any, error) {
+	return map[string]any{}, nil
+}
+func (f *fakeBackend) Close() error { return nil }
+func (f *fakeBackend) CallTool(_ context.Context, tool string, _ map[string]any) (map[string]any, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[tool]++
+	return map[string]any{"tool": tool}, nil
+}
+
+func TestCacheSeparatesTools(t *testing.T) {
+	cases := []struct {
+		name string
+		spec map[string]CacheSpec
+	}{
+		{"wildcard", map[string]CacheSpec{"*": {TTL: "1m"}}},
+		{"per tool", map[string]CacheSpec{"list_indices": {TTL: "1m"}, "cluster_health": {TTL: "1m"}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewAggregator()
+			a.setCaches("es", tc.spec)
+			bk := &fakeBackend{calls: map[string]int{}}
+			for _, tool := range []string{"list_indices", "cluster_health", "list_indices", "cluster_health"} {
+				res, _, err := a.callCached(context.Background(), bk, tool, map[string]any{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res["tool"] != tool {
+					t.Fatalf("call %s returned the result of %v", tool, res["tool"])
+				}
+			}
+			// 每个工具只访问后端一次，第二次命中各自的缓存
+			for _, tool := range []string{"list_indices", "cluster_health"} {
+				if bk.calls[tool] != 1 {
+					t.Fatalf("%s reached the backend %d times, want 1", tool, bk.calls[tool])
+				}
+			}
+		})
+	}
+}
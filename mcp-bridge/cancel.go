@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// cancelledParams 是发给后端的 notifications/cancelled 参数
+func cancelledParams(id string, reason error) map[string]any {
+	return map[string]any{"requestId": id, "reason": reason.Error()}
+}
+
+// track 登记会话中一个进行中的请求，客户端发 notifications/cancelled 时据此取消；返回注销函数
+func (se *session) track(id string, cancel context.CancelFunc) func() {
+	se.mu.Lock()
+	if se.inflight == nil {
+		se.inflight = map[string]context.CancelFunc{}
+	}
+	se.inflight[id] = cancel
+	se.mu.Unlock()
+	return func() {
+		se.mu.Lock()
+		delete(se.inflight, id)
+		se.mu.Unlock()
+	}
+}
+func (se *session) cancel(id string) bool {
+	se.mu.Lock()
+	cancel := se.inflight[id]
+	se.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return cancel != nil
+}
+
+// rpcStream 串行化同一个 POST 响应上的写入：客户端接受 SSE 时，进度通知先作为事件发出，
+// 最后才是响应本身；finish 之后迟到的通知直接丢弃。
+// 通知经缓冲队列由单独的 goroutine 写出，客户端读得慢时丢弃而不是阻塞后端的读循环
+type rpcStream struct {
+	mu       sync.Mutex
+	w        http.ResponseWriter
+	r        *http.Request
+	finished bool
+	queue    chan map[string]any
+	drained  chan struct{}
+}
+
+func (st *rpcStream) notify(msg map[string]any) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.finished {
+		return
+	}
+	if st.queue == nil {
+		st.queue, st.drained = make(chan map[string]any, streamBuffer), make(chan struct{})
+		go st.drain()
+	}
+	select {
+	case st.queue <- msg:
+	default:
+	}
+}
+func (st *rpcStream) drain() {
+	defer close(st.drained)
+	for msg := range st.queue {
+		if fl, ok := sseHeaders(st.w); ok {
+			writeSSEEvent(st.w, fl, "message", msg)
+		}
+	}
+}
+func (st *rpcStream) finish(v any) {
+	st.mu.Lock()
+	st.finished = true
+	if st.queue != nil {
+		close(st.queue)
+	}
+	st.mu.Unlock()
+	// 等已排队的进度写完再写响应
+	if st.drained != nil {
+		<-st.drained
+	}
+	if v == nil {
+		st.w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	replyRPC(st.w, st.r, v)
+}
+
+type streamKey struct{}
+
+func withStream(ctx context.Context, st *rpcStream) context.Context {
+	return context.WithValue(ctx, streamKey{}, st)
+}
+
+// progressSink 决定一次调用的进度通知发到哪里：优先当前 POST 的 SSE 响应，其次会话的 GET 事件流
+func (a *Aggregator) progressSink(ctx context.Context) func(map[string]any) {
+	if st, _ := ctx.Value(streamKey{}).(*rpcStream); st != nil && wantsSSE(st.r) {
+		return st.notify
+	}
+	if se := sessionFrom(ctx); se != nil {
+		return func(msg map[string]any) { a.publish(se.ID, msg) }
+	}
+	return nil
+}
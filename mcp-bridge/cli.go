@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const cliUsage = `usage: mcp-bridge [command] [flags]
+
+commands:
+  serve                      start the HTTP server (default)
+  validate                   check the config file and exit
+  list-tools                 start the backends, print the aggregated tools with schemas and exit
+  call <tool> [json-args]    run one tools/call through the aggregator and print the result;
+                             json-args defaults to {} and "-" reads it from stdin
+
+run "mcp-bridge <command> -h" for the flags of a command
+`
+
+// runCommand 处理子命令；返回 false 表示没有子命令，照常启动服务
+func runCommand(args []string) (int, bool) {
+	if len(args) == 0 || args[0] == "serve" {
+		return 0, false
+	}
+	switch args[0] {
+	case "validate":
+		return cmdValidate(args[1:]), true
+	case "list-tools":
+		return cmdListTools(args[1:]), true
+	case "call":
+		return cmdCall(args[1:]), true
+	case "help", "-h", "-help", "--help":
+		fmt.Print(cliUsage)
+		return 0, true
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], cliUsage)
+	return 2, true
+}
+
+// cliOpts 是需要启动后端的子命令共用的参数
+type cliOpts struct {
+	config string
+	wait   time.Duration
+	client string
+	quiet  bool
+}
+
+func newFlagSet(name string, o *cliOpts, withBackends bool) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&o.config, "config", cfgPath, "config file (env MCP_CONFIG)")
+	if withBackends {
+		fs.DurationVar(&o.wait, "wait", 60*time.Second, "how long to wait for backends to become ready")
+		fs.IntVar(&initRetry, "retry", 1, "start attempts per backend (env INIT_RETRY applies to serve only)")
+		fs.StringVar(&o.client, "client", "localhost", "client identity used for policies")
+		fs.BoolVar(&o.quiet, "quiet", false, "hide bridge and backend logs")
+	}
+	return fs
+}
+func cmdValidate(args []string) int {
+	var o cliOpts
+	fs := newFlagSet("validate", &o, false)
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	c, err := loadConfig(o.config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	problems := checkServers(c)
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	abs, _ := filepath.Abs(o.config)
+	if len(problems) > 0 {
+		fmt.Printf("%s: %d problem(s)\n", abs, len(problems))
+		return 1
+	}
+	fmt.Printf("%s: ok, %d server(s)\n", abs, len(c.Servers))
+	return 0
+}
+
+// checkServers 检查 loadConfig 不管的部分：传输类型、命令和 URL；禁用的后端跳过
+func checkServers(c *Config) []string {
+	names := make([]string, 0, len(c.Servers))
+	for raw := range c.Servers {
+		names = append(names, raw)
+	}
+	sort.Strings(names)
+	var out []string
+	for _, raw := range names {
+		sp := c.Servers[raw]
+		if sp.Disabled {
+			continue
+		}
+		where := "mcpServers." + raw
+		switch kind := backendKind(sp); kind {
+		case "stdio":
+			if sp.Command == "" {
+				out = append(out, where+": missing command")
+				break
+			}
+			if _, err := exec.LookPath(sp.Command); err != nil {
+				out = append(out, fmt.Sprintf("%s: command %q not found", where, sp.Command))
+			}
+			// 常见错误是 wrapper 脚本的相对路径不对
+			if len(sp.Args) > 0 && isScript(sp.Args[0]) {
+				if _, err := os.Stat(sp.Args[0]); err != nil {
+					out = append(out, fmt.Sprintf("%s: script %q not found", where, sp.Args[0]))
+				}
+			}
+		case "http", "sse":
+			u, err := url.Parse(sp.URL)
+			switch {
+			case sp.URL == "":
+				out = append(out, fmt.Sprintf("%s: %s transport needs url", where, kind))
+			case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+				out = append(out, fmt.Sprintf("%s: bad url %q", where, secrets.scrub(sp.URL)))
+			}
+		default:
+			out = append(out, fmt.Sprintf("%s: unsupported transport %q (stdio, http or sse)", where, kind))
+		}
+	}
+	return out
+}
+func isScript(arg string) bool {
+	switch filepath.Ext(arg) {
+	case ".py", ".js", ".mjs", ".ts", ".sh":
+		return true
+	}
+	return false
+}
+
+// startBackends 按配置启动后端并等待就绪；返回的错误说明哪些后端没有就绪，此时 Aggregator 仍可使用
+func startBackends(ctx context.Context, c *Config, o cliOpts) (*Aggregator, error) {
+	if o.quiet {
+		log.SetOutput(io.Discard)
+	}
+	agg := NewAggregator()
+	if err := agg.StartFromConfig(c); err != nil {
+		return agg, err
+	}
+	wctx, cancel := context.WithTimeout(ctx, o.wait)
+	defer cancel()
+	return agg, agg.WaitReady(wctx)
+}
+func cmdListTools(args []string) int {
+	var o cliOpts
+	fs := newFlagSet("list-tools", &o, true)
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	c, err := loadConfig(o.config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	agg, werr := startBackends(ctx, c, o)
+	defer agg.Close()
+	printJSON(os.Stdout, agg.ListExported(o.client))
+	if werr != nil {
+		fmt.Fprintln(os.Stderr, werr)
+		return 1
+	}
+	return 0
+}
+func cmdCall(args []string) int {
+	var o cliOpts
+	fs := newFlagSet("call", &o, true)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mcp-bridge call [flags] <tool> [json-args]")
+		fs.PrintDefaults()
+	}
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+	tool, raw := fs.Arg(0), "{}"
+	if fs.NArg() == 2 {
+		raw = fs.Arg(1)
+	}
+	if raw == "-" {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		raw = string(b)
+	}
+	var toolArgs map[string]any
+	if err := json.Unmarshal([]byte(raw), &toolArgs); err != nil {
+		fmt.Fprintf(os.Stderr, "bad json-args: %v\n", err)
+		return 2
+	}
+	c, err := loadConfig(o.config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	ownerOnly(c, tool)
+	// Ctrl-C 取消调用，取消会照常转发给后端
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	agg, werr := startBackends(ctx, c, o)
+	defer agg.Close()
+	if werr != nil {
+		fmt.Fprintln(os.Stderr, werr)
+	}
+	res, err := agg.Call(withClient(ctx, o.client), tool, toolArgs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		var re *rpcErr
+		if errors.As(err, &re) && re.Data != nil {
+			printJSON(os.Stderr, re.Data)
+		}
+		return 1
+	}
+	printJSON(os.Stdout, res)
+	if res["isError"] == true {
+		return 1
+	}
+	return 0
+}
+
+// ownerOnly 在能从工具名（别名或命名空间前缀）确定后端时只保留该后端，省去启动其他后端的时间
+func ownerOnly(c *Config, tool string) {
+	owner := ""
+	for raw, al := range c.Aliases {
+		for _, ta := range al.Tools {
+			for _, name := range ta.Aliases {
+				if name == tool {
+					owner = raw
+				}
+			}
+		}
+	}
+	if i := strings.Index(tool, "."); owner == "" && i > 0 {
+		for raw := range c.Servers {
+			if c.namespace(raw) == tool[:i] {
+				owner = raw
+			}
+		}
+	}
+	if sp, ok := c.Servers[owner]; ok {
+		c.Servers = map[string]SrvSpec{owner: sp}
+	}
+}
+func printJSON(w io.Writer, v any) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
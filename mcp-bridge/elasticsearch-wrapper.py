@@ -6,6 +6,8 @@ import os
 
 def read_message():
     line = sys.stdin.readline()
+    if not line:
+        return None
     if line.startswith('Content-Length:'):
         length = int(line.split(':')[1].strip())
         sys.stdin.readline()
@@ -32,6 +34,7 @@ def main():
     ], stdin=subprocess.PIPE, stdout=subprocess.PIPE, env=env, text=True, bufsize=0)
     
     while True:
+        req = None
         try:
             req = read_message()
             if not req: break
@@ -40,13 +43,24 @@ def main():
             proc.stdin.write(json_str)
             proc.stdin.flush()
             
-            resp_line = proc.stdout.readline()
-            if resp_line.strip():
+            # Notifications (initialized, cancelled) get no reply; waiting for one would block forever
+            if req.get("id") is None:
+                continue
+            
+            while True:
+                resp_line = proc.stdout.readline()
+                if not resp_line:
+                    raise RuntimeError("upstream server exited")
+                if not resp_line.strip():
+                    continue
                 resp = json.loads(resp_line.strip())
                 send_message(resp)
+                if resp.get("id") == req.get("id") and "method" not in resp:
+                    break
                 
         except Exception as e:
-            send_message({"jsonrpc":"2.0","id":req.get("id"),"error":{"code":-32603,"message":str(e)}})
+            if req and req.get("id") is not None:
+                send_message({"jsonrpc":"2.0","id":req.get("id"),"error":{"code":-32603,"message":str(e)}})
 
 if __name__ == "__main__":
     main()
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitSpec 是后端的令牌桶限流；超出速率的调用立即失败
+type RateLimitSpec struct {
+	RPS   float64 `json:"rps"`
+	Burst int     `json:"burst,omitempty"`
+}
+
+// BreakerSpec 是后端的熔断配置：连续 Failures 次失败或超时后熔断 Cooldown，
+// 之后放行 HalfOpenProbes 个探测调用，探测成功即恢复
+type BreakerSpec struct {
+	Failures       int    `json:"failures,omitempty"`
+	Cooldown       string `json:"cooldown,omitempty"`
+	HalfOpenProbes int    `json:"halfOpenProbes,omitempty"`
+}
+
+const (
+	codeRateLimited = -32004
+	codeCircuitOpen = -32005
+)
+
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+func (b BreakerSpec) cooldown() (time.Duration, error) {
+	if b.Cooldown == "" {
+		return 30 * time.Second, nil
+	}
+	d, err := time.ParseDuration(b.Cooldown)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("bad cooldown %q", b.Cooldown)
+	}
+	return d, nil
+}
+func validateGuards(servers map[string]SrvSpec) error {
+	for name, sp := range servers {
+		if r := sp.RateLimit; r != nil && (r.RPS <= 0 || r.Burst < 0) {
+			return fmt.Errorf("mcpServers.%s.rateLimit: rps must be positive", name)
+		}
+		if b := sp.CircuitBreaker; b != nil {
+			if _, err := b.cooldown(); err != nil {
+				return fmt.Errorf("mcpServers.%s.circuitBreaker: %w", name, err)
+			}
+			if b.Failures < 0 || b.HalfOpenProbes < 0 {
+				return fmt.Errorf("mcpServers.%s.circuitBreaker: counts must not be negative", name)
+			}
+		}
+	}
+	return nil
+}
+
+// backendGuard 在调用进入后端前做限流和熔断判断；两者都没配置时为 nil
+type backendGuard struct {
+	name string
+	mu   sync.Mutex
+
+	rps    float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	breaker   bool
+	threshold int
+	cool      time.Duration
+	probes    int
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   int
+}
+
+func newBackendGuard(name string, sp SrvSpec) *backendGuard {
+	if sp.RateLimit == nil && sp.CircuitBreaker == nil {
+		return nil
+	}
+	g := &backendGuard{name: name, state: breakerClosed}
+	if r := sp.RateLimit; r != nil {
+		g.rps, g.burst = r.RPS, float64(r.Burst)
+		if g.burst < 1 {
+			g.burst = math.Max(1, math.Ceil(r.RPS))
+		}
+		g.tokens, g.last = g.burst, time.Now()
+	}
+	if b := sp.CircuitBreaker; b != nil {
+		g.breaker = true
+		g.threshold, g.probes = b.Failures, b.HalfOpenProbes
+		if g.threshold == 0 {
+			g.threshold = 5
+		}
+		if g.probes == 0 {
+			g.probes = 1
+		}
+		g.cool, _ = b.cooldown()
+	}
+	return g
+}
+
+// acquire 返回 nil 表示可以调用；调用结束后必须调用 release 报告结果
+func (g *backendGuard) acquire() (probe bool, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	if g.breaker {
+		switch g.state {
+		case breakerOpen:
+			if wait := g.cool - now.Sub(g.openedAt); wait > 0 {
+				bridgeMetrics.guardRejected(g.name, "circuit_open")
+				return false, &rpcErr{Code: codeCircuitOpen, Message: fmt.Sprintf("backend %s is unavailable (circuit open after %d consecutive failures), retry in %s", g.name, g.failures, wait.Round(time.Second))}
+			}
+			g.state, g.probing = breakerHalfOpen, 0
+			fallthrough
+		case breakerHalfOpen:
+			if g.probing >= g.probes {
+				bridgeMetrics.guardRejected(g.name, "circuit_open")
+				return false, &rpcErr{Code: codeCircuitOpen, Message: fmt.Sprintf("backend %s is unavailable (circuit half-open, probe in progress)", g.name)}
+			}
+			probe = true
+		}
+	}
+	if g.rps > 0 {
+		g.tokens = math.Min(g.burst, g.tokens+now.Sub(g.last).Seconds()*g.rps)
+		g.last = now
+		if g.tokens < 1 {
+			bridgeMetrics.guardRejected(g.name, "rate_limited")
+			return false, &rpcErr{Code: codeRateLimited, Message: fmt.Sprintf("backend %s is rate limited (%g calls/s)", g.name, g.rps)}
+		}
+		g.tokens--
+	}
+	if probe {
+		g.probing++
+	}
+	return probe, nil
+}
+
+// release 报告调用结果：传输错误和超时计为失败；调用方主动取消和后端应答的 JSON-RPC error
+// （参数错误、未知工具等，说明后端在正常应答）既不计为失败也不清零计数；工具返回 isError 视为成功
+func (g *backendGuard) release(probe bool, err error) {
+	if !g.breaker {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if probe {
+		g.probing--
+	}
+	var re *remoteError
+	if errors.Is(err, context.Canceled) || errors.As(err, &re) {
+		return
+	}
+	if err == nil {
+		g.failures, g.state = 0, breakerClosed
+		return
+	}
+	g.failures++
+	if g.state == breakerHalfOpen || g.failures >= g.threshold {
+		g.state, g.openedAt = breakerOpen, time.Now()
+	}
+}
+func (g *backendGuard) breakerState() string {
+	if g == nil || !g.breaker {
+		return ""
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.state == breakerOpen && time.Since(g.openedAt) >= g.cool {
+		return breakerHalfOpen
+	}
+	return g.state
+}
+func (a *Aggregator) guardFor(name string) *backendGuard {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if sv := a.sup[name]; sv != nil {
+		return sv.guard
+	}
+	return nil
+}
+
+// callBackend 经过限流和熔断后调用后端
+func (a *Aggregator) callBackend(ctx context.Context, bk Backend, orig string, args map[string]any) (map[string]any, error) {
+	g := a.guardFor(bk.Name())
+	if g == nil {
+		return bk.CallTool(ctx, orig, args)
+	}
+	probe, err := g.acquire()
+	if err != nil {
+		return nil, err
+	}
+	res, err := bk.CallTool(ctx, orig, args)
+	g.release(probe, err)
+	return res, err
+}
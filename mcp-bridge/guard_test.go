@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestBreakerTransitions 按顺序执行一串调用结果，检查每一步之后的熔断状态；
+// "cool" 表示冷却时间已过，"rejected" 表示这次调用被熔断直接拒绝
+func TestBreakerTransitions(t *testing.T) {
+	results := map[string]error{
+		"ok":      nil,
+		"fail":    errors.New("broken pipe"),
+		"timeout": context.DeadlineExceeded,
+		"remote":  &remoteError{"unknown tool: x"},
+		"cancel":  context.Canceled,
+	}
+	cases := []struct {
+		name  string
+		steps []string
+		want  []string
+	}{
+		{"opens after threshold", []string{"fail", "fail"}, []string{breakerClosed, breakerOpen}},
+		{"timeouts count", []string{"timeout", "timeout"}, []string{breakerClosed, breakerOpen}},
+		{"success resets count", []string{"fail", "ok", "fail"}, []string{breakerClosed, breakerClosed, breakerClosed}},
+		{"remote errors do not count", []string{"remote", "remote", "remote"}, []string{breakerClosed, breakerClosed, breakerClosed}},
+		{"remote errors do not reset", []string{"fail", "remote", "fail"}, []string{breakerClosed, breakerClosed, breakerOpen}},
+		{"cancel does not count", []string{"cancel", "cancel", "fail"}, []string{breakerClosed, breakerClosed, breakerClosed}},
+		{"open rejects calls", []string{"fail", "fail", "ok"}, []string{breakerClosed, breakerOpen, "rejected"}},
+		{"half-open after cooldown", []string{"fail", "fail", "cool"}, []string{breakerClosed, breakerOpen, breakerHalfOpen}},
+		{"probe success closes", []string{"fail", "fail", "cool", "ok"}, []string{breakerClosed, breakerOpen, breakerHalfOpen, breakerClosed}},
+		{"probe failure reopens", []string{"fail", "fail", "cool", "fail", "ok"}, []string{breakerClosed, breakerOpen, breakerHalfOpen, breakerOpen, "rejected"}},
+		{"remote error probe stays half-open", []string{"fail", "fail", "cool", "remote", "ok"}, []string{breakerClosed, breakerOpen, breakerHalfOpen, breakerHalfOpen, breakerClosed}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := newBackendGuard("t", SrvSpec{CircuitBreaker: &BreakerSpec{Failures: 2}})
+			for i, step := range tc.steps {
+				got := ""
+				if step == "cool" {
+					g.mu.Lock()
+					g.openedAt = g.openedAt.Add(-g.cool)
+					g.mu.Unlock()
+					got = g.breakerState()
+				} else if probe, err := g.acquire(); err != nil {
+					var re *rpcErr
+					if !errors.As(err, &re) || re.Code != codeCircuitOpen {
+						t.Fatalf("step %d: unexpected error %v", i, err)
+					}
+					got = "rejected"
+				} else {
+					g.release(probe, results[step])
+					got = g.breakerState()
+				}
+				if got != tc.want[i] {
+					t.Fatalf("step %d (%s): state %s, want %s", i, step, got, tc.want[i])
+				}
+			}
+		})
+	}
+}
+func TestBreakerHalfOpenProbeLimit(t *testing.T) {
+	g := newBackendGuard("t", SrvSpec{CircuitBreaker: &BreakerSpec{Failures: 1, HalfOpenProbes: 1}})
+	probe, _ := g.acquire()
+	g.release(probe, errors.New("down"))
+	g.mu.Lock()
+	g.openedAt = g.openedAt.Add(-g.cool)
+	g.mu.Unlock()
+	probe, err := g.acquire()
+	if err != nil || !probe {
+		t.Fatalf("first call after cooldown: probe=%v err=%v, want a probe", probe, err)
+	}
+	// 探测还没结束时其他调用被拒绝
+	if _, err := g.acquire(); err == nil {
+		t.Fatal("second call during probe was allowed")
+	}
+	g.release(probe, nil)
+	if _, err := g.acquire(); err != nil {
+		t.Fatalf("call after successful probe: %v", err)
+	}
+}
+func TestRateLimit(t *testing.T) {
+	g := newBackendGuard("t", SrvSpec{RateLimit: &RateLimitSpec{RPS: 0.001, Burst: 2}})
+	for i := 0; i < 2; i++ {
+		if _, err := g.acquire(); err != nil {
+			t.Fatalf("call %d within burst: %v", i, err)
+		}
+	}
+	_, err := g.acquire()
+	var re *rpcErr
+	if !errors.As(err, &re) || re.Code != codeRateLimited {
+		t.Fatalf("call over burst: %v, want code %d", err, codeRateLimited)
+	}
+	if g.breakerState() != "" {
+		t.Fatalf("breaker state %q without circuitBreaker", g.breakerState())
+	}
+}
+func TestNoGuardWithoutConfig(t *testing.T) {
+	if g := newBackendGuard("t", SrvSpec{}); g != nil {
+		t.Fatal("guard created without rateLimit or circuitBreaker")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// pingInterval 为 0 时不 ping
+	pingInterval = getenvDur("PING_INTERVAL", 30*time.Second)
+	pingTimeout  = getenvDur("PING_TIMEOUT", 10*time.Second)
+)
+
+func validateRequired(servers map[string]SrvSpec) error {
+	for name, sp := range servers {
+		if sp.Required && sp.Disabled {
+			return fmt.Errorf("mcpServers.%s: required backend must not be disabled", name)
+		}
+	}
+	return nil
+}
+
+// pingBackends 定时向就绪的后端发送 MCP ping，记录最近一次应答的时间；
+// 结果只用于观测，就绪与否仍以后端状态为准，避免繁忙的后端（ping 也占 maxConcurrent）被误判
+func (a *Aggregator) pingBackends() {
+	if pingInterval <= 0 {
+		return
+	}
+	t := time.NewTicker(pingInterval)
+	defer t.Stop()
+	for range t.C {
+		a.pingAll()
+	}
+}
+func (a *Aggregator) pingAll() {
+	a.mu.RLock()
+	targets := map[string]Backend{}
+	for name, bk := range a.backends {
+		if sv := a.sup[name]; sv != nil && sv.status.State == stateReady {
+			targets[name] = bk
+		}
+	}
+	a.mu.RUnlock()
+	var wg sync.WaitGroup
+	for name, bk := range targets {
+		wg.Add(1)
+		go func(name string, bk Backend) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+			defer cancel()
+			_, err := bk.Request(ctx, "ping", map[string]any{})
+			// 返回 JSON-RPC 错误（比如不支持 ping）也说明后端在应答
+			var re *remoteError
+			if errors.As(err, &re) {
+				err = nil
+			}
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			sv := a.sup[name]
+			if sv == nil || a.backends[name] != bk {
+				return
+			}
+			if err != nil {
+				sv.status.PingError = secrets.scrub(err.Error())
+				return
+			}
+			sv.status.LastPing, sv.status.PingError = time.Now().Unix(), ""
+		}(name, bk)
+	}
+	wg.Wait()
+}
+
+// readyBackend 是 /readyz 中每个后端的信息；readyz 不需要认证，错误信息、PID 等只在 /admin/backends 中提供
+type readyBackend struct {
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	LastPing int64  `json:"lastPing,omitempty"`
+	Breaker  string `json:"breaker,omitempty"`
+}
+
+// handleReadyz 在所有 required 后端就绪前返回 503；没有 required 后端时总是 200
+func (s *httpServer) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	waiting, backends := []string{}, []readyBackend{}
+	for _, st := range s.agg.Status() {
+		if st.Required && st.State != stateReady {
+			waiting = append(waiting, st.Name)
+		}
+		backends = append(backends, readyBackend{Name: st.Name, State: st.State, LastPing: st.LastPing, Breaker: st.Breaker})
+	}
+	code := http.StatusOK
+	if len(waiting) > 0 {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{"ready": len(waiting) == 0, "waiting": waiting, "backends": backends, "ts": time.Now().Unix()})
+}
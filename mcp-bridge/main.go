@@ -4,37 +4,53 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
 
 type Config struct {
-	Servers map[string]SrvSpec `json:"mcpServers"`
+	Servers  map[string]SrvSpec    `json:"mcpServers"`
+	Auth     *AuthSpec             `json:"auth,omitempty"`
+	Policies map[string]ToolPolicy `json:"policies,omitempty"`
+	Aliases  map[string]AliasSpec  `json:"aliases,omitempty"`
 }
 type SrvSpec struct {
-	Command       string            `json:"command,omitempty"`
-	Args          []string          `json:"args,omitempty"`
-	Env           map[string]string `json:"env,omitempty"`
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	// EnvAllow 是 stdio 子进程可以从 bridge 继承的环境变量（glob），见 childEnv
+	EnvAllow      []string          `json:"envAllow,omitempty"`
 	TransportType string            `json:"transportType,omitempty"`
 	Type          string            `json:"type,omitempty"`
 	Disabled      bool              `json:"disabled,omitempty"`
 	URL           string            `json:"url,omitempty"`
 	Headers       map[string]string `json:"headers,omitempty"`
+	MaxConcurrent int               `json:"maxConcurrent,omitempty"`
+	// Required 的后端就绪之前 /readyz 返回 503
+	Required bool `json:"required,omitempty"`
+	// Cache 按原始工具名（或 "*"）配置结果缓存
+	Cache          map[string]CacheSpec `json:"cache,omitempty"`
+	RateLimit      *RateLimitSpec       `json:"rateLimit,omitempty"`
+	CircuitBreaker *BreakerSpec         `json:"circuitBreaker,omitempty"`
+	// OutputBudget 按原始工具名（或 "*"）限制返回给客户端的结果大小
+	OutputBudget map[string]BudgetSpec `json:"outputBudget,omitempty"`
 }
 type rpcReq struct {
 	JSONRPC string          `json:"jsonrpc"`
@@ -51,11 +67,19 @@ type rpcResp struct {
 type rpcErr struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
 }
+
+func (e *rpcErr) Error() string { return e.Message }
+
 type ToolItem struct {
-	Name        string         `json:"name"`
-	Description string         `json:"description,omitempty"`
-	InputSchema map[string]any `json:"inputSchema,omitempty"`
+	Name         string         `json:"name"`
+	Title        string         `json:"title,omitempty"`
+	Description  string         `json:"description,omitempty"`
+	InputSchema  map[string]any `json:"inputSchema,omitempty"`
+	OutputSchema map[string]any `json:"outputSchema,omitempty"`
+	Annotations  map[string]any `json:"annotations,omitempty"`
+	Meta         map[string]any `json:"_meta,omitempty"`
 }
 
 var (
@@ -83,6 +107,16 @@ func getenvInt(k string, def int) int {
 	}
 	return def
 }
+func getenvBool(k string, def bool) bool {
+	v := strings.TrimSpace(os.Getenv(k))
+	if v == "" {
+		return def
+	}
+	if b, err := strconv.ParseBool(v); err == nil {
+		return b
+	}
+	return def
+}
 func getenvDur(k string, def time.Duration) time.Duration {
 	v := strings.TrimSpace(os.Getenv(k))
 	if v == "" {
@@ -144,28 +178,70 @@ func redactArgs(a []string) []string {
 			}
 		}
 	}
+	// 配置中解析出的密钥可能出现在任何参数里
+	for i := range o {
+		o[i] = secrets.scrub(o[i])
+	}
 	return o
 }
 
 type Backend interface {
 	Name() string
 	Initialize(context.Context) error
+	// Capabilities 返回后端在 initialize 响应中声明的 capabilities
+	Capabilities() map[string]any
 	ListTools(context.Context) ([]ToolItem, error)
 	CallTool(context.Context, string, map[string]any) (map[string]any, error)
+	// Request 发送任意 JSON-RPC 请求（resources/*、prompts/* 等）
+	Request(ctx context.Context, method string, params map[string]any) (map[string]any, error)
 	Close() error
 }
+
+// callSlots 限制单个后端同时在途的请求数；nil 表示不限制
+type callSlots chan struct{}
+
+func newCallSlots(n int) callSlots {
+	if n <= 0 {
+		return nil
+	}
+	return make(callSlots, n)
+}
+func (c callSlots) acquire(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	select {
+	case c <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+func (c callSlots) release() {
+	if c != nil {
+		<-c
+	}
+}
+
 type stdioBackend struct {
-	name    string
-	cmd     *exec.Cmd
-	stdin   io.WriteCloser
-	stdout  io.ReadCloser
-	reader  *bufio.Reader
-	closed  chan struct{}
-	pending map[string]chan map[string]any
-	pm      sync.Mutex
-	rpcMu   sync.Mutex
-	seq     int64
-	sm      sync.Mutex
+	name      string
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	stdout    io.ReadCloser
+	reader    *bufio.Reader
+	closed    chan struct{}
+	closeOnce sync.Once
+	exited    chan struct{}
+	exitErr   error
+	waitOnce  sync.Once
+	pending   map[string]chan map[string]any
+	pm        sync.Mutex
+	wm        sync.Mutex
+	slots     callSlots
+	seq       int64
+	sm        sync.Mutex
+	caps      map[string]any
+	notifyHub
 }
 
 func newStdioBackend(name string, s SrvSpec) (*stdioBackend, error) {
@@ -173,10 +249,7 @@ func newStdioBackend(name string, s SrvSpec) (*stdioBackend, error) {
 		return nil, fmt.Errorf("%s: missing command", name)
 	}
 	cmd := exec.Command(s.Command, s.Args...)
-	cmd.Env = os.Environ()
-	for k, v := range s.Env {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
-	}
+	cmd.Env = childEnv(s)
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return nil, err
@@ -199,11 +272,12 @@ func newStdioBackend(name string, s SrvSpec) (*stdioBackend, error) {
 			}
 		}()
 	}
-	return &stdioBackend{name: name, cmd: cmd, stdin: stdin, stdout: stdout, reader: bufio.NewReader(stdout), closed: make(chan struct{}), pending: make(map[string]chan map[string]any)}, nil
+	sb := &stdioBackend{name: name, cmd: cmd, stdin: stdin, stdout: stdout, reader: bufio.NewReader(stdout), closed: make(chan struct{}), exited: make(chan struct{}), pending: make(map[string]chan map[string]any), slots: newCallSlots(s.MaxConcurrent)}
+	go sb.readLoop()
+	return sb, nil
 }
 func (s *stdioBackend) Name() string { return s.name }
 func (s *stdioBackend) Initialize(ctx context.Context) error {
-	go s.readLoop()
 	params := map[string]any{
 		"protocolVersion": "2024-11-05",
 		"capabilities": map[string]any{
@@ -214,8 +288,16 @@ func (s *stdioBackend) Initialize(ctx context.Context) error {
 			"version": "0.3.0",
 		},
 	}
-	_, err := s.rpc(ctx, "initialize", params)
-	return err
+	res, err := s.rpc(ctx, "initialize", params)
+	if err != nil {
+		return err
+	}
+	s.caps, _ = res["capabilities"].(map[string]any)
+	return nil
+}
+func (s *stdioBackend) Capabilities() map[string]any { return s.caps }
+func (s *stdioBackend) Request(ctx context.Context, method string, params map[string]any) (map[string]any, error) {
+	return s.rpc(ctx, method, params)
 }
 func (s *stdioBackend) ListTools(ctx context.Context) ([]ToolItem, error) {
 	res, err := s.rpc(ctx, "tools/list", map[string]any{})
@@ -228,26 +310,42 @@ func (s *stdioBackend) ListTools(ctx context.Context) ([]ToolItem, error) {
 	return out, nil
 }
 func (s *stdioBackend) CallTool(ctx context.Context, tool string, args map[string]any) (map[string]any, error) {
-	return s.rpc(ctx, "tools/call", map[string]any{"name": tool, "arguments": args})
+	return s.rpc(ctx, "tools/call", callParams(ctx, tool, args))
 }
 func (s *stdioBackend) Close() error {
-	select {
-	case <-s.closed:
-	default:
-		close(s.closed)
-	}
+	s.markClosed()
 	if s.cmd != nil && s.cmd.Process != nil {
 		_ = s.cmd.Process.Signal(syscall.SIGTERM)
-		done := make(chan struct{})
-		go func() { _ = s.cmd.Wait(); close(done) }()
+		go s.wait()
 		select {
-		case <-done:
+		case <-s.exited:
 		case <-time.After(3 * time.Second):
 			_ = s.cmd.Process.Kill()
+			<-s.exited
 		}
 	}
 	return nil
 }
+func (s *stdioBackend) Done() <-chan struct{} { return s.closed }
+func (s *stdioBackend) PID() int {
+	if s.cmd.Process == nil {
+		return 0
+	}
+	return s.cmd.Process.Pid
+}
+func (s *stdioBackend) ExitStatus() string {
+	select {
+	case <-s.exited:
+		if s.exitErr != nil {
+			return s.exitErr.Error()
+		}
+		return "exit status 0"
+	default:
+		return "running"
+	}
+}
+func (s *stdioBackend) markClosed() { s.closeOnce.Do(func() { close(s.closed) }) }
+func (s *stdioBackend) wait()       { s.waitOnce.Do(func() { s.exitErr = s.cmd.Wait(); close(s.exited) }) }
 func (s *stdioBackend) nextID() string {
 	s.sm.Lock()
 	defer s.sm.Unlock()
@@ -255,8 +353,20 @@ func (s *stdioBackend) nextID() string {
 	return strconv.FormatInt(s.seq, 10)
 }
 func (s *stdioBackend) rpc(ctx context.Context, method string, params map[string]any) (map[string]any, error) {
-	s.rpcMu.Lock()
-	defer s.rpcMu.Unlock()
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if err := s.slots.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.slots.release()
+	select {
+	case <-s.closed:
+		return nil, errors.New("backend closed")
+	default:
+	}
 	id := s.nextID()
 	req := map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
 	raw, _ := json.Marshal(req)
@@ -264,40 +374,47 @@ func (s *stdioBackend) rpc(ctx context.Context, method string, params map[string
 	s.pm.Lock()
 	s.pending[id] = ch
 	s.pm.Unlock()
+	defer s.removePending(id)
 	if err := s.writeFrame(raw); err != nil {
-		s.removePending(id)
 		return nil, err
 	}
 	select {
 	case <-ctx.Done():
-		s.removePending(id)
+		if method != "initialize" {
+			go s.cancelRemote(id, ctx.Err())
+		}
 		return nil, ctx.Err()
 	case <-s.closed:
-		s.removePending(id)
 		return nil, errors.New("backend closed")
 	case resp := <-ch:
-		if e, ok := resp["error"].(map[string]any); ok {
-			return nil, fmt.Errorf("%v", e["message"])
-		}
-		if r, ok := resp["result"].(map[string]any); ok {
-			return r, nil
-		}
-		return map[string]any{"result": resp["result"]}, nil
+		return rpcResult(resp)
 	}
 }
+
+// remoteError 是后端应答的 JSON-RPC error，用来与超时、连接断开等传输错误区分
+type remoteError struct{ message string }
+
+func (e *remoteError) Error() string { return e.message }
+
+// rpcResult 从一条 JSON-RPC 响应中取出 result 或转换 error
+func rpcResult(resp map[string]any) (map[string]any, error) {
+	if e, ok := resp["error"].(map[string]any); ok {
+		return nil, &remoteError{fmt.Sprint(e["message"])}
+	}
+	if r, ok := resp["result"].(map[string]any); ok {
+		return r, nil
+	}
+	return map[string]any{"result": resp["result"]}, nil
+}
 func (s *stdioBackend) removePending(id string) { s.pm.Lock(); delete(s.pending, id); s.pm.Unlock() }
 func (s *stdioBackend) readLoop() {
 	for {
 		payload, err := s.readFrame()
 		if err != nil {
-			if !errors.Is(err, io.EOF) {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrClosed) {
 				log.Printf("[%s] read error: %v", s.name, err)
 			}
-			select {
-			case <-s.closed:
-			default:
-				close(s.closed)
-			}
+			s.markClosed()
 			return
 		}
 		var msg map[string]any
@@ -305,10 +422,15 @@ func (s *stdioBackend) readLoop() {
 			log.Printf("[%s] bad json: %v", s.name, err)
 			continue
 		}
-		id := fmt.Sprint(msg["id"])
-		if id == "" {
+		if method, _ := msg["method"].(string); method != "" {
+			if msg["id"] == nil {
+				s.emit(msg)
+			} else {
+				go s.answer(msg)
+			}
 			continue
 		}
+		id := fmt.Sprint(msg["id"])
 		s.pm.Lock()
 		ch := s.pending[id]
 		if ch != nil {
@@ -318,6 +440,24 @@ func (s *stdioBackend) readLoop() {
 		s.pm.Unlock()
 	}
 }
+
+// cancelRemote 通知后端放弃已经不再等待的请求
+func (s *stdioBackend) cancelRemote(id string, reason error) {
+	raw, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "method": "notifications/cancelled", "params": cancelledParams(id, reason)})
+	_ = s.writeFrame(raw)
+}
+
+// answer 回应后端发来的请求：bridge 只支持 ping，其余回 Method not found
+func (s *stdioBackend) answer(msg map[string]any) {
+	resp := map[string]any{"jsonrpc": "2.0", "id": msg["id"]}
+	if msg["method"] == "ping" {
+		resp["result"] = map[string]any{}
+	} else {
+		resp["error"] = map[string]any{"code": -32601, "message": "Method not found"}
+	}
+	raw, _ := json.Marshal(resp)
+	_ = s.writeFrame(raw)
+}
 func (s *stdioBackend) readFrame() ([]byte, error) {
 	cl := 0
 	var firstLine string
@@ -361,6 +501,8 @@ func (s *stdioBackend) readFrame() ([]byte, error) {
 	return body, nil
 }
 func (s *stdioBackend) writeFrame(p []byte) error {
+	s.wm.Lock()
+	defer s.wm.Unlock()
 	var b bytes.Buffer
 	fmt.Fprintf(&b, "Content-Length: %d\r\n\r\n", len(p))
 	b.Write(p)
@@ -368,23 +510,35 @@ func (s *stdioBackend) writeFrame(p []byte) error {
 	return err
 }
 
+// httpBackend 实现 MCP Streamable HTTP 客户端：
+// 每个请求独立 id，响应可以是 JSON 或 SSE 流，并回放服务端下发的 Mcp-Session-Id
 type httpBackend struct {
-	name    string
-	url     string
-	headers map[string]string
-	client  *http.Client
+	name     string
+	url      string
+	headers  map[string]string
+	client   *http.Client
+	slots    callSlots
+	seq      int64
+	sm       sync.Mutex
+	session  string
+	protocol string
+	caps     map[string]any
+	notifyHub
 }
 
+const clientProtocolVersion = "2025-06-18"
+
 func newHTTPBackend(name string, sp SrvSpec) (*httpBackend, error) {
 	if sp.URL == "" {
 		return nil, fmt.Errorf("%s: http missing url", name)
 	}
-	return &httpBackend{name: name, url: sp.URL, headers: sp.Headers, client: &http.Client{Timeout: timeout}}, nil
+	return &httpBackend{name: name, url: sp.URL, headers: sp.Headers, client: &http.Client{Timeout: timeout}, slots: newCallSlots(sp.MaxConcurrent)}, nil
 }
 func (h *httpBackend) Name() string { return h.name }
 func (h *httpBackend) Initialize(ctx context.Context) error {
+	h.setSession("", "")
 	params := map[string]any{
-		"protocolVersion": "2024-11-05",
+		"protocolVersion": clientProtocolVersion,
 		"capabilities": map[string]any{
 			"tools": map[string]any{},
 		},
@@ -393,8 +547,23 @@ func (h *httpBackend) Initialize(ctx context.Context) error {
 			"version": "0.3.0",
 		},
 	}
-	_, err := h.rpc(ctx, "initialize", params)
-	return err
+	res, err := h.roundTrip(ctx, "initialize", params)
+	if err != nil {
+		return err
+	}
+	h.sm.Lock()
+	h.protocol, _ = res["protocolVersion"].(string)
+	h.caps, _ = res["capabilities"].(map[string]any)
+	h.sm.Unlock()
+	return h.notify(ctx, "notifications/initialized", nil)
+}
+func (h *httpBackend) Capabilities() map[string]any {
+	h.sm.Lock()
+	defer h.sm.Unlock()
+	return h.caps
+}
+func (h *httpBackend) Request(ctx context.Context, method string, params map[string]any) (map[string]any, error) {
+	return h.rpc(ctx, method, params)
 }
 func (h *httpBackend) ListTools(ctx context.Context) ([]ToolItem, error) {
 	res, err := h.rpc(ctx, "tools/list", map[string]any{})
@@ -407,17 +576,42 @@ func (h *httpBackend) ListTools(ctx context.Context) ([]ToolItem, error) {
 	return out, nil
 }
 func (h *httpBackend) CallTool(ctx context.Context, tool string, args map[string]any) (map[string]any, error) {
-	return h.rpc(ctx, "tools/call", map[string]any{"name": tool, "arguments": args})
+	return h.rpc(ctx, "tools/call", callParams(ctx, tool, args))
 }
-func (h *httpBackend) rpc(ctx context.Context, method string, params map[string]any) (map[string]any, error) {
-	req := rpcReq{JSONRPC: "2.0", ID: json.RawMessage(`1`), Method: method}
-	if params != nil {
-		b, _ := json.Marshal(params)
-		req.Params = b
+func (h *httpBackend) nextID() string {
+	h.sm.Lock()
+	defer h.sm.Unlock()
+	h.seq++
+	return strconv.FormatInt(h.seq, 10)
+}
+func (h *httpBackend) setSession(id, protocol string) {
+	h.sm.Lock()
+	h.session, h.protocol = id, protocol
+	h.sm.Unlock()
+}
+func (h *httpBackend) sessionID() string {
+	h.sm.Lock()
+	defer h.sm.Unlock()
+	return h.session
+}
+
+// post 发送一条 JSON-RPC 消息，附带会话与协议版本头，并记录服务端返回的会话 id
+func (h *httpBackend) post(ctx context.Context, msg any) (*http.Response, error) {
+	body, _ := json.Marshal(msg)
+	rq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
 	}
-	body, _ := json.Marshal(req)
-	rq, _ := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
 	rq.Header.Set("Content-Type", "application/json")
+	rq.Header.Set("Accept", "application/json, text/event-stream")
+	h.sm.Lock()
+	if h.session != "" {
+		rq.Header.Set("Mcp-Session-Id", h.session)
+	}
+	if h.protocol != "" {
+		rq.Header.Set("MCP-Protocol-Version", h.protocol)
+	}
+	h.sm.Unlock()
 	for k, v := range h.headers {
 		rq.Header.Set(k, v)
 	}
@@ -425,40 +619,296 @@ func (h *httpBackend) rpc(ctx context.Context, method string, params map[string]
 	if err != nil {
 		return nil, err
 	}
+	if sid := resp.Header.Get("Mcp-Session-Id"); sid != "" {
+		h.sm.Lock()
+		h.session = sid
+		h.sm.Unlock()
+	}
+	return resp, nil
+}
+
+// cancelRemote 通知后端放弃已经不再等待的请求
+func (h *httpBackend) cancelRemote(id string, reason error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = h.notify(ctx, "notifications/cancelled", cancelledParams(id, reason))
+}
+func (h *httpBackend) notify(ctx context.Context, method string, params map[string]any) error {
+	msg := map[string]any{"jsonrpc": "2.0", "method": method}
+	if params != nil {
+		msg["params"] = params
+	}
+	resp, err := h.post(ctx, msg)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("http %d on %s", resp.StatusCode, method)
+	}
+	return nil
+}
+func (h *httpBackend) rpc(ctx context.Context, method string, params map[string]any) (map[string]any, error) {
+	if err := h.slots.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer h.slots.release()
+	res, err := h.roundTrip(ctx, method, params)
+	if errors.Is(err, errSessionExpired) && method != "initialize" {
+		// 会话过期（服务端返回 404）：重新握手后重试一次
+		log.Printf("[%s] session expired, re-initializing", h.name)
+		if err := h.Initialize(ctx); err != nil {
+			return nil, err
+		}
+		return h.roundTrip(ctx, method, params)
+	}
+	return res, err
+}
+
+var errSessionExpired = errors.New("mcp session expired")
+
+func (h *httpBackend) roundTrip(ctx context.Context, method string, params map[string]any) (res map[string]any, err error) {
+	id := h.nextID()
+	defer func() {
+		if err != nil && ctx.Err() != nil && method != "initialize" {
+			go h.cancelRemote(id, ctx.Err())
+		}
+	}()
+	msg := map[string]any{"jsonrpc": "2.0", "id": id, "method": method}
+	if params != nil {
+		msg["params"] = params
+	}
+	hadSession := h.sessionID() != ""
+	resp, err := h.post(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && hadSession {
+		return nil, errSessionExpired
+	}
 	if resp.StatusCode >= 400 {
-		b, _ := io.ReadAll(resp.Body)
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
 		return nil, fmt.Errorf("http %d: %s", resp.StatusCode, string(b))
 	}
-	var r rpcResp
-	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+	ct := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(ct, "text/event-stream") {
+		var out map[string]any
+		err := readSSE(resp.Body, func(event, data string) bool {
+			if event != "" && event != "message" {
+				return true
+			}
+			var m map[string]any
+			if json.Unmarshal([]byte(data), &m) != nil {
+				return true
+			}
+			// 响应流中夹带的通知（如 progress）交给 bridge
+			if m["method"] != nil && m["id"] == nil {
+				h.emit(m)
+				return true
+			}
+			if fmt.Sprint(m["id"]) == id && (m["result"] != nil || m["error"] != nil) {
+				out = m
+				return false
+			}
+			return true
+		})
+		if out != nil {
+			return rpcResult(out)
+		}
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("sse stream ended before response: %w", err)
+	}
+	var m map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
 		return nil, err
 	}
-	if r.Error != nil {
-		return nil, errors.New(r.Error.Message)
+	return rpcResult(m)
+}
+func (h *httpBackend) Close() error {
+	sid := h.sessionID()
+	if sid == "" {
+		return nil
 	}
-	switch v := r.Result.(type) {
-	case map[string]any:
-		return v, nil
-	default:
-		return map[string]any{"result": v}, nil
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	rq, err := http.NewRequestWithContext(ctx, http.MethodDelete, h.url, nil)
+	if err != nil {
+		return nil
+	}
+	rq.Header.Set("Mcp-Session-Id", sid)
+	for k, v := range h.headers {
+		rq.Header.Set(k, v)
+	}
+	if resp, err := h.client.Do(rq); err == nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// readSSE 逐条解析 text/event-stream，fn 返回 false 时停止读取
+func readSSE(r io.Reader, fn func(event, data string) bool) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	var event string
+	var data []string
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			if len(data) > 0 && !fn(event, strings.Join(data, "\n")) {
+				return nil
+			}
+			event, data = "", nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
 	}
+	if len(data) > 0 {
+		fn(event, strings.Join(data, "\n"))
+	}
+	return nil
 }
-func (h *httpBackend) Close() error { return nil }
 
+// toolRef 是一个导出名（规范名或别名）对应的工具；canon 是规范名 "<命名空间>.<原名>"
+type toolRef struct {
+	srv   string
+	orig  string
+	canon string
+	item  ToolItem
+}
 type Aggregator struct {
 	backends map[string]Backend
-	tools    map[string][2]string
+	tools    map[string]toolRef
+	catalogs map[string]*catalog
+	names    map[string]string
+	aliases  map[string]AliasSpec
+	progress *progressRoute
+	// sink 把通知发给 /mcp 会话，由 httpServer 设置
+	sink     func(sid string, msg map[string]any)
+	sup      map[string]*supervised
+	parked   map[string]*supervised // 被管理接口停用的后端，保留停用前的 supervisor
+	policies map[string]ToolPolicy
+	audit    *auditLog
+	caches   map[toolKey]*toolCache
+	cacheMu  sync.Mutex
 	mu       sync.RWMutex
+	reloadMu sync.Mutex
 }
 
 func NewAggregator() *Aggregator {
-	return &Aggregator{backends: map[string]Backend{}, tools: map[string][2]string{}}
+	return &Aggregator{backends: map[string]Backend{}, tools: map[string]toolRef{}, catalogs: map[string]*catalog{}, progress: &progressRoute{m: map[string]progressTarget{}}, sup: map[string]*supervised{}, parked: map[string]*supervised{}, caches: map[toolKey]*toolCache{}}
+}
+func backendKind(sp SrvSpec) string {
+	kind := strings.ToLower(strings.TrimSpace(sp.TransportType))
+	if kind == "" {
+		kind = strings.ToLower(strings.TrimSpace(sp.Type))
+	}
+	if kind == "" {
+		kind = "stdio"
+	}
+	return kind
+}
+func newBackend(name string, sp SrvSpec) (Backend, error) {
+	switch kind := backendKind(sp); kind {
+	case "stdio":
+		return newStdioBackend(name, sp)
+	case "http":
+		return newHTTPBackend(name, sp)
+	case "sse":
+		return newSSEBackend(name, sp)
+	default:
+		return nil, fmt.Errorf("unsupported transport: %s", kind)
+	}
+}
+
+// launch 创建后端并完成 initialize + tools/list（以及资源、prompt 列表）；失败时负责关闭已创建的后端
+func (a *Aggregator) launch(name string, sp SrvSpec) (Backend, *catalog, error) {
+	bk, err := newBackend(name, sp)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n, ok := bk.(notifier); ok {
+		n.SetNotify(func(msg map[string]any) { a.onNotify(name, bk, msg) })
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := bk.Initialize(ctx); err != nil {
+		_ = bk.Close()
+		if w, ok := bk.(exitWatcher); ok {
+			return nil, nil, fmt.Errorf("initialize: %v (%s)", err, w.ExitStatus())
+		}
+		return nil, nil, fmt.Errorf("initialize: %w", err)
+	}
+	ctx2, cancel2 := context.WithTimeout(context.Background(), timeout)
+	defer cancel2()
+	tools, err := bk.ListTools(ctx2)
+	if err != nil {
+		_ = bk.Close()
+		return nil, nil, fmt.Errorf("tools/list: %w", err)
+	}
+	cat := &catalog{tools: tools}
+	listExtras(ctx2, bk, cat)
+	return bk, cat, nil
+}
+
+// install 原子地替换某个后端及其工具，返回被替换下来的旧后端；
+// sv 已不是该名字的当前 supervisor 时不做替换（ok=false）
+func (a *Aggregator) install(name string, sv *supervised, bk Backend, cat *catalog) (old Backend, ok bool) {
+	a.mu.Lock()
+	if a.sup[name] != sv || sv.stopped() {
+		a.mu.Unlock()
+		return nil, false
+	}
+	old, oldCat := a.backends[name], a.catalogs[name]
+	a.backends[name] = bk
+	a.setCatalog(name, cat)
+	sv.status.Tools = len(cat.tools)
+	sv.status.ReadySince = time.Now().Unix()
+	a.mu.Unlock()
+	a.catalogChanged(oldCat, cat)
+	return old, true
 }
+
+// setCatalog 替换某个后端的工具、资源和 prompt；调用方需持有 a.mu 写锁
+func (a *Aggregator) setCatalog(name string, cat *catalog) {
+	a.dropTools(name)
+	a.exportTools(name, cat.tools)
+	a.catalogs[name] = cat
+}
+
+// dropTools 同时移除该后端的资源和 prompt；调用方需持有 a.mu 写锁
+func (a *Aggregator) dropTools(name string) {
+	for exp, p := range a.tools {
+		if p.srv == name {
+			delete(a.tools, exp)
+		}
+	}
+	delete(a.catalogs, name)
+}
+
+// StartFromConfig 并发启动所有后端后立即返回，后端就绪后其工具才出现在 tools/list 中
 func (a *Aggregator) StartFromConfig(c *Config) error {
 	if c == nil {
 		return fmt.Errorf("config is nil")
 	}
+	a.applyPolicies(c.Policies)
+	a.applyAliases(c)
 	if len(c.Servers) == 0 {
 		log.Printf("[bridge] no MCP servers configured, starting with empty aggregator")
 		return nil
@@ -468,174 +918,168 @@ func (a *Aggregator) StartFromConfig(c *Config) error {
 			log.Printf("[%s] disabled -> skip", raw)
 			continue
 		}
-		kind := strings.ToLower(strings.TrimSpace(sp.TransportType))
-		if kind == "" {
-			kind = strings.ToLower(strings.TrimSpace(sp.Type))
-		}
-		if kind == "" {
-			kind = "stdio"
-		}
-		name := sanitizeName(raw)
-		var bk Backend
-		var err error
-		switch kind {
-		case "stdio":
-			bk, err = newStdioBackend(name, sp)
-		case "http":
-			bk, err = newHTTPBackend(name, sp)
-		default:
-			err = fmt.Errorf("unsupported transport: %s", kind)
-		}
-		if err != nil {
-			log.Printf("[%s] backend create failed: %v", raw, err)
-			continue
-		}
-		a.backends[name] = bk
-		var initErr error
-		for i := 0; i < initRetry; i++ {
-			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			initErr = bk.Initialize(ctx)
-			cancel()
-			if initErr == nil {
-				break
-			}
-			time.Sleep(time.Second)
-		}
-		if initErr != nil {
-			log.Printf("[%s] initialize failed after retries: %v", raw, initErr)
-			continue
-		}
-		var tools []ToolItem
-		{
-			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			tl, err := bk.ListTools(ctx)
-			cancel()
-			if err != nil {
-				log.Printf("[%s] tools/list failed: %v", raw, err)
-				continue
-			}
-			tools = tl
-		}
-		for _, t := range tools {
-			exp := name + "." + t.Name
-			a.tools[exp] = [2]string{name, t.Name}
-		}
-		log.Printf("[%s] ready, tools: %d", raw, len(tools))
+		a.start(raw, sanitizeName(raw), sp)
 	}
 	return nil
 }
-func (a *Aggregator) ListExported() []ToolItem {
+
+// ListExported 返回 client 有权使用的工具，按名字排序
+func (a *Aggregator) ListExported(client string) []ToolItem {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 	out := make([]ToolItem, 0, len(a.tools))
 	for exp, p := range a.tools {
-		out = append(out, ToolItem{Name: exp, Description: fmt.Sprintf("From %s -> %s", p[0], p[1]), InputSchema: map[string]any{"type": "object"}})
+		if !a.allowed(client, exp, p.canon) {
+			continue
+		}
+		t := p.item
+		t.Name = exp
+		if t.Description == "" {
+			t.Description = fmt.Sprintf("From %s -> %s", p.srv, p.orig)
+		}
+		if t.InputSchema == nil {
+			t.InputSchema = map[string]any{"type": "object"}
+		}
+		out = append(out, t)
 	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
 	return out
 }
-func (a *Aggregator) Call(ctx context.Context, name string, args map[string]any) (map[string]any, error) {
+
+func (a *Aggregator) inputSchema(exp string) map[string]any {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.tools[exp].item.InputSchema
+}
+
+// resolve 返回后端、原始工具名和规范的导出名；别名解析到它指向的工具，
+// 策略同时检查别名和规范名
+func (a *Aggregator) resolve(client, name string) (bk Backend, orig, exp string, err error) {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
-	var srv, orig string
+	var srv string
 	if p, ok := a.tools[name]; ok {
-		srv, orig = p[0], p[1]
+		srv, orig, exp = p.srv, p.orig, p.canon
 	} else if !strings.Contains(name, ".") {
-		c := make([][2]string, 0, 2)
-		for exp, p := range a.tools {
-			if strings.HasSuffix(exp, "."+name) {
-				c = append(c, p)
+		c := map[string]toolRef{}
+		for e, p := range a.tools {
+			if strings.HasSuffix(e, "."+name) && a.allowed(client, e, p.canon) {
+				c[p.canon] = p
 			}
 		}
-		if len(c) == 1 {
-			srv, orig = c[0][0], c[0][1]
-		} else {
-			return nil, fmt.Errorf("unknown or ambiguous tool: %s", name)
+		if len(c) != 1 {
+			return nil, "", "", fmt.Errorf("unknown or ambiguous tool: %s", name)
 		}
+		for _, p := range c {
+			srv, orig, exp = p.srv, p.orig, p.canon
+		}
+		name = exp
 	} else {
-		return nil, fmt.Errorf("unknown tool: %s", name)
+		return nil, "", "", fmt.Errorf("unknown tool: %s", name)
+	}
+	if !a.allowed(client, name, exp) {
+		return nil, "", "", &rpcErr{Code: codeToolDenied, Message: fmt.Sprintf("tool %s is not allowed for client %s", exp, client)}
 	}
-	bk := a.backends[srv]
-	if bk == nil {
-		return nil, fmt.Errorf("backend missing: %s", srv)
+	if bk = a.backends[srv]; bk == nil {
+		return nil, "", "", fmt.Errorf("backend missing: %s", srv)
 	}
-	return bk.CallTool(ctx, orig, args)
+	return bk, orig, exp, nil
+}
+func (a *Aggregator) Call(ctx context.Context, name string, args map[string]any) (map[string]any, error) {
+	start := time.Now()
+	bk, orig, exp, err := a.resolve(clientFrom(ctx), name)
+	if err != nil {
+		a.recordCall(ctx, name, "", "", args, start, nil, false, err)
+		return nil, err
+	}
+	if validateArgs {
+		if err := checkArgs(exp, a.inputSchema(exp), args); err != nil {
+			a.recordCall(ctx, exp, bk.Name(), orig, args, start, nil, false, err)
+			return nil, err
+		}
+	}
+	if tok, ok := metaFrom(ctx)["progressToken"]; ok {
+		if deliver := a.progressSink(ctx); deliver != nil {
+			bt, remove := a.progress.add(tok, deliver)
+			defer remove()
+			ctx = withMeta(ctx, withField(metaFrom(ctx), "progressToken", bt))
+		}
+	}
+	done := bridgeMetrics.callStarted(bk.Name(), orig)
+	res, cached, err := a.callCached(ctx, bk, orig, args)
+	if err == nil {
+		res = a.applyBudget(bk.Name(), orig, res)
+	}
+	done(err != nil || res["isError"] == true)
+	a.recordCall(ctx, exp, bk.Name(), orig, args, start, res, cached, err)
+	return res, err
 }
 func (a *Aggregator) Close() {
+	a.mu.Lock()
+	for _, sv := range a.sup {
+		sv.halt()
+	}
+	bks := make([]Backend, 0, len(a.backends))
 	for _, bk := range a.backends {
+		bks = append(bks, bk)
+	}
+	a.mu.Unlock()
+	for _, bk := range bks {
 		_ = bk.Close()
 	}
 }
 
 type httpServer struct {
-	agg     *Aggregator
-	timeout time.Duration
-	mux     *http.ServeMux
+	agg      *Aggregator
+	timeout  time.Duration
+	mux      *http.ServeMux
+	sessions *sessionStore
+	auth     atomic.Pointer[authenticator]
+	tls      *tls.Config
 }
 
 func newHTTP(agg *Aggregator) *httpServer {
-	s := &httpServer{agg: agg, timeout: timeout, mux: http.NewServeMux()}
+	s := &httpServer{agg: agg, timeout: timeout, mux: http.NewServeMux(), sessions: newSessionStore(sessionIdleTTL)}
+	au, _ := newAuthenticator(nil)
+	s.auth.Store(au)
+	agg.sink = s.sessions.deliver
 	s.routes()
 	return s
 }
-func (s *httpServer) routes() {
-	// 认证跳过中间件
-	authSkipMiddleware := func(next http.HandlerFunc) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
-			// 检查是否来自本机
-			clientIP := r.RemoteAddr
-			if host, _, err := net.SplitHostPort(clientIP); err == nil {
-				clientIP = host
-			}
-
-			// 跳过认证的条件：
-			// 1. 来自127.0.0.1或::1
-			// 2. 带有X-Internal-Call: 1 header
-			skipAuth := clientIP == "127.0.0.1" || clientIP == "::1" ||
-				r.Header.Get("X-Internal-Call") == "1"
-
-			if skipAuth {
-				// 跳过认证，直接处理请求
-				next(w, r)
-				return
-			}
 
-			// 其他情况需要认证（这里可以添加OAuth逻辑）
-			next(w, r)
-		}
+// applyConfig 更新认证配置；TLS 只在启动时生效
+func (s *httpServer) applyConfig(c *Config) error {
+	au, err := newAuthenticator(c.Auth)
+	if err != nil {
+		return err
 	}
-
+	s.auth.Store(au)
+	log.Printf("[bridge] auth: %s", au.describe())
+	return nil
+}
+func (s *httpServer) routes() {
+	// healthz 只表示进程存活；后端是否可用看 readyz
 	s.mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "tools": len(s.agg.tools), "ts": time.Now().Unix()})
+		writeJSON(w, map[string]any{"ok": true, "ts": time.Now().Unix()})
 	})
+	s.mux.HandleFunc("/readyz", s.handleReadyz)
 
-	s.mux.HandleFunc("/register", authSkipMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok","message":"registration successful"}`))
-	}))
-	s.mux.HandleFunc("/authorize", authSkipMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok","message":"auth skipped for localhost"}`))
-	}))
+	s.mux.HandleFunc("/metrics", s.requireAuth(s.handleMetrics))
+	s.mux.HandleFunc("/admin/calls", s.requireAdmin(s.handleCalls))
+	s.mux.HandleFunc("/admin/backends", s.requireAdmin(s.handleBackends))
+	s.mux.HandleFunc("/admin/backends/", s.requireAdmin(s.handleBackends))
 
-	// 添加OAuth相关路径的通配符处理
-	s.mux.HandleFunc("/oauth/", authSkipMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok","message":"oauth skipped for localhost"}`))
-	}))
-
-	s.mux.HandleFunc("/token", authSkipMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"access_token":"dummy","token_type":"Bearer"}`))
-	}))
-
-	s.mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+	s.mux.HandleFunc("/mcp", s.requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get("MCP-Protocol-Version"); v != "" && !protocolSupported(v) {
+			http.Error(w, "Bad Request: unsupported MCP-Protocol-Version "+v, http.StatusBadRequest)
+			return
+		}
 		switch r.Method {
 		case http.MethodGet:
+			se, ok := s.sessions.lookup(w, r)
+			if !ok {
+				return
+			}
 			// 长连（Server -> Client）SSE 通道；兼容老示例，先发 endpoint 事件
 			fl, ok := sseHeaders(w)
 			if !ok {
@@ -644,107 +1088,121 @@ func (s *httpServer) routes() {
 			}
 			// 兼容老客户端/文档：告知消息端点（你就是 /mcp）
 			writeSSEEvent(w, fl, "endpoint", "/mcp")
-			// keepalive，避免某些代理/客户端断开
-			ticker := time.NewTicker(25 * time.Second)
-			defer ticker.Stop()
-			for {
-				select {
-				case <-r.Context().Done():
-					return
-				case <-ticker.C:
-					fmt.Fprint(w, ":keepalive\n\n")
-					fl.Flush()
-				}
-			}
+			// 之后推送后端通知；keepalive 避免某些代理/客户端断开
+			streamNotifications(r.Context(), se, func(msg map[string]any) {
+				writeSSEEvent(w, fl, "message", msg)
+			}, func() {
+				fmt.Fprint(w, ":keepalive\n\n")
+				fl.Flush()
+			})
 
 		case http.MethodPost:
-			// 读取一条 JSON-RPC 消息
-			var req rpcReq
-			dec := json.NewDecoder(r.Body)
-			if err := dec.Decode(&req); err != nil {
-				// Q CLI 对 content-type 比较挑剔：当 Accept 含 SSE 时，用 SSE 回错误更稳妥
-				if wantsSSE(r) {
-					writeSSEMessage(w, map[string]any{
-						"jsonrpc": "2.0",
-						"error":   map[string]any{"code": -32700, "message": "Parse error"},
-					})
-				} else {
-					writeJSON(w, rpcResp{JSONRPC: "2.0", Error: &rpcErr{Code: -32700, Message: "Parse error"}})
-				}
+			// 读取一条 JSON-RPC 消息，或一个批量数组
+			var raw json.RawMessage
+			if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+				replyRPC(w, r, rpcResp{JSONRPC: "2.0", Error: &rpcErr{Code: -32700, Message: "Parse error"}})
 				return
 			}
-
-			// 通知（没有 id）必须 202，无响应体（符合 MCP 规范）
-			if len(req.ID) == 0 {
-				// 特别处理 notifications/initialized：接受即可，不要回错误
-				if req.Method == "notifications/initialized" {
-					w.WriteHeader(http.StatusAccepted)
+			var batch []json.RawMessage
+			isBatch := bytes.HasPrefix(bytes.TrimSpace(raw), []byte("["))
+			if isBatch {
+				if err := json.Unmarshal(raw, &batch); err != nil {
+					replyRPC(w, r, rpcResp{JSONRPC: "2.0", Error: &rpcErr{Code: -32700, Message: "Parse error"}})
 					return
 				}
-				// 任何其它通知也接受（你也可按需校验 Method 再 400）
-				w.WriteHeader(http.StatusAccepted)
-				return
+				if len(batch) == 0 {
+					replyRPC(w, r, rpcResp{JSONRPC: "2.0", Error: &rpcErr{Code: -32600, Message: "Invalid Request: empty batch"}})
+					return
+				}
+			} else {
+				batch = []json.RawMessage{raw}
 			}
-
-			// 有 id 的请求：根据 Accept 决定回 SSE 还是 JSON（为兼容 Q，优先 SSE）
-			reply := func(result map[string]any, errObj *rpcErr) {
-				resp := rpcResp{JSONRPC: "2.0", ID: req.ID}
-				if errObj != nil {
-					resp.Error = errObj
-				} else {
-					resp.Result = result
+			reqs := make([]*rpcReq, len(batch))
+			replies := make([]bool, len(batch))
+			for i, m := range batch {
+				var req struct {
+					rpcReq
+					Result json.RawMessage `json:"result"`
+					Error  json.RawMessage `json:"error"`
 				}
-				if wantsSSE(r) {
-					writeSSEMessage(w, resp)
-				} else {
-					writeJSON(w, resp)
+				switch {
+				case json.Unmarshal(m, &req) != nil:
+				case req.Method != "":
+					reqs[i] = &req.rpcReq
+					bridgeMetrics.rpcRequest(req.Method)
+				case len(req.ID) > 0 && (req.Result != nil || req.Error != nil):
+					// 客户端回给我们的响应：bridge 不会发起请求，接受后忽略
+					replies[i] = true
 				}
 			}
 
-			// 业务方法分发
-			switch req.Method {
-			case "initialize":
-				reply(map[string]any{
-					"protocolVersion": "2025-06-18",
-					"capabilities":    map[string]any{"tools": map[string]any{}},
-					"serverInfo":      map[string]any{"name": "mcp-http-bridge", "version": "0.3.0"},
-				}, nil)
-
-			case "tools/list":
-				tools := s.agg.ListExported()
-				// 统一成规范返回
-				result := map[string]any{"tools": tools}
-				reply(result, nil)
-
-			case "tools/call":
-				var p struct {
-					Name      string         `json:"name"`
-					Arguments map[string]any `json:"arguments"`
-				}
-				if len(req.Params) > 0 {
-					if err := json.Unmarshal(req.Params, &p); err != nil {
-						reply(nil, &rpcErr{Code: -32602, Message: "Invalid params"})
-						return
-					}
-				}
-				ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
-				defer cancel()
-				res, err := s.agg.Call(ctx, p.Name, p.Arguments)
-				if err != nil {
-					reply(nil, &rpcErr{Code: -32000, Message: err.Error()})
+			// initialize 之外的请求都要校验会话；initialize 不能出现在批量请求里
+			var sess *session
+			if isBatch || reqs[0] == nil || reqs[0].Method != "initialize" {
+				se, ok := s.sessions.lookup(w, r)
+				if !ok {
 					return
 				}
-				reply(res, nil)
+				sess = se
+			}
+			stream := &rpcStream{w: w, r: r}
+			ctx := withStream(withSession(r.Context(), sess), stream)
+
+			// 批量中的 tools/call 互相独立，并发执行；其余消息按顺序处理
+			resps := make([]*rpcResp, len(reqs))
+			var wg sync.WaitGroup
+			for i, req := range reqs {
+				switch {
+				case replies[i]:
+				case req == nil:
+					resps[i] = &rpcResp{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcErr{Code: -32600, Message: "Invalid Request"}}
+				case isBatch && req.Method == "initialize":
+					resps[i] = &rpcResp{JSONRPC: "2.0", ID: req.ID, Error: &rpcErr{Code: -32600, Message: "initialize must not be part of a batch"}}
+				case isBatch && req.Method == "tools/call":
+					wg.Add(1)
+					go func(i int, req *rpcReq) {
+						defer wg.Done()
+						resps[i] = s.dispatch(ctx, w, req)
+					}(i, req)
+				default:
+					resps[i] = s.dispatch(ctx, w, req)
+				}
+			}
+			wg.Wait()
 
+			out := make([]rpcResp, 0, len(resps))
+			for _, resp := range resps {
+				if resp != nil {
+					out = append(out, *resp)
+				}
+			}
+			// 全部是通知（没有 id）时必须 202，无响应体（符合 MCP 规范）
+			switch {
+			case len(out) == 0:
+				stream.finish(nil)
+			case isBatch:
+				stream.finish(out)
 			default:
-				// 未知方法：规范错误
-				reply(nil, &rpcErr{Code: -32601, Message: "Method not found"})
+				stream.finish(out[0])
+			}
+
+		case http.MethodDelete:
+			// 客户端主动结束会话
+			if r.Header.Get("Mcp-Session-Id") == "" {
+				http.Error(w, "Bad Request: missing Mcp-Session-Id", http.StatusBadRequest)
+				return
+			}
+			se, ok := s.sessions.lookup(w, r)
+			if !ok {
+				return
 			}
+			s.sessions.remove(se.ID)
+			w.WriteHeader(http.StatusNoContent)
 
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-	})
+	}))
 }
 func writeRPC(w http.ResponseWriter, resp rpcResp) {
 	w.Header().Set("Content-Type", "application/json")
@@ -752,6 +1210,147 @@ func writeRPC(w http.ResponseWriter, resp rpcResp) {
 }
 
 // --- add helpers for SSE and Accept handling ---
+// replyRPC 根据 Accept 决定回 SSE 还是 JSON（为兼容 Q，优先 SSE）
+func replyRPC(w http.ResponseWriter, r *http.Request, v any) {
+	if wantsSSE(r) {
+		writeSSEMessage(w, v)
+	} else {
+		writeJSON(w, v)
+	}
+}
+
+// dispatch 处理一条 JSON-RPC 消息；通知返回 nil
+func (s *httpServer) dispatch(ctx context.Context, w http.ResponseWriter, req *rpcReq) *rpcResp {
+	sess := sessionFrom(ctx)
+	if len(req.ID) == 0 {
+		// 特别处理 notifications/initialized：接受即可，不要回错误
+		if req.Method == "notifications/initialized" && sess != nil {
+			sess.markInitialized()
+		}
+		// 客户端放弃了某个请求：取消对应的后端调用
+		if req.Method == "notifications/cancelled" && sess != nil {
+			var p struct {
+				RequestID json.RawMessage `json:"requestId"`
+			}
+			if json.Unmarshal(req.Params, &p) == nil && sess.cancel(string(p.RequestID)) {
+				log.Printf("[bridge] session %s cancelled request %s", sess.ID, p.RequestID)
+			}
+		}
+		// 任何其它通知也接受（你也可按需校验 Method 再 400）
+		return nil
+	}
+	// 握手完成（收到 notifications/initialized）之前只接受 initialize 和 ping
+	if sessionRequired && sess != nil && !sess.Initialized() && req.Method != "initialize" && req.Method != "ping" {
+		return &rpcResp{JSONRPC: "2.0", ID: req.ID, Error: &rpcErr{Code: -32600, Message: "Invalid Request: notifications/initialized not received"}}
+	}
+	reply := func(result map[string]any, errObj *rpcErr) *rpcResp {
+		resp := &rpcResp{JSONRPC: "2.0", ID: req.ID}
+		if errObj != nil {
+			resp.Error = errObj
+		} else {
+			resp.Result = result
+		}
+		return resp
+	}
+
+	// 业务方法分发
+	switch req.Method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string         `json:"protocolVersion"`
+			Capabilities    map[string]any `json:"capabilities"`
+			ClientInfo      map[string]any `json:"clientInfo"`
+		}
+		if len(req.Params) > 0 {
+			_ = json.Unmarshal(req.Params, &p)
+		}
+		sess = s.sessions.create(clientFrom(ctx), negotiateProtocol(p.ProtocolVersion), p.ClientInfo, p.Capabilities)
+		w.Header().Set("Mcp-Session-Id", sess.ID)
+		log.Printf("[bridge] session %s initialized by %v as %s (protocol %s)", sess.ID, p.ClientInfo["name"], sess.Client, sess.ProtocolVersion)
+		return reply(map[string]any{
+			"protocolVersion": sess.ProtocolVersion,
+			"capabilities":    s.agg.Capabilities(),
+			"serverInfo":      map[string]any{"name": "mcp-http-bridge", "version": "0.3.0"},
+		}, nil)
+
+	case "tools/list":
+		tools := s.agg.ListExported(clientFrom(ctx))
+		// 统一成规范返回
+		return reply(map[string]any{"tools": tools}, nil)
+
+	case "tools/call":
+		var p struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+			Meta      map[string]any `json:"_meta"`
+		}
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, &p); err != nil {
+				return reply(nil, &rpcErr{Code: -32602, Message: "Invalid params"})
+			}
+		}
+		ctx, cancel := context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+		if sess != nil {
+			defer sess.track(string(req.ID), cancel)()
+		}
+		if p.Meta[noCacheMeta] == true {
+			ctx = withNoCache(ctx)
+		}
+		ctx = withMeta(ctx, p.Meta)
+		res, err := s.agg.Call(ctx, p.Name, p.Arguments)
+		if err != nil {
+			var re *rpcErr
+			if errors.As(err, &re) {
+				return reply(nil, re)
+			}
+			return reply(nil, &rpcErr{Code: -32000, Message: err.Error()})
+		}
+		return reply(res, nil)
+
+	case "resources/list":
+		return reply(map[string]any{"resources": s.agg.ListResources()}, nil)
+
+	case "resources/templates/list":
+		return reply(map[string]any{"resourceTemplates": s.agg.ListResourceTemplates()}, nil)
+
+	case "prompts/list":
+		return reply(map[string]any{"prompts": s.agg.ListPrompts()}, nil)
+
+	case "resources/read", "prompts/get":
+		var p struct {
+			URI       string         `json:"uri"`
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, &p); err != nil {
+				return reply(nil, &rpcErr{Code: -32602, Message: "Invalid params"})
+			}
+		}
+		ctx, cancel := context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+		var res map[string]any
+		var err error
+		if req.Method == "resources/read" {
+			res, err = s.agg.ReadResource(ctx, p.URI)
+		} else {
+			res, err = s.agg.GetPrompt(ctx, p.Name, p.Arguments)
+		}
+		if err != nil {
+			var re *rpcErr
+			if errors.As(err, &re) {
+				return reply(nil, re)
+			}
+			return reply(nil, &rpcErr{Code: -32000, Message: err.Error()})
+		}
+		return reply(res, nil)
+
+	default:
+		// 未知方法：规范错误
+		return reply(nil, &rpcErr{Code: -32601, Message: "Method not found"})
+	}
+}
 func wantsSSE(r *http.Request) bool {
 	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
 }
@@ -797,30 +1396,93 @@ func (s *httpServer) serve(addr string) error {
 		_ = httpSrv.Shutdown(context.Background())
 		close(idle)
 	}()
-	log.Printf("[bridge] listening on %s", addr)
-	if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	var err error
+	if s.tls != nil {
+		httpSrv.TLSConfig = s.tls
+		log.Printf("[bridge] listening on %s (tls)", addr)
+		err = httpSrv.ListenAndServeTLS("", "")
+	} else {
+		log.Printf("[bridge] listening on %s", addr)
+		err = httpSrv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
 	<-idle
 	return nil
 }
-func main() {
-	abs, _ := filepath.Abs(cfgPath)
-	log.Printf("[bridge] loading %s", abs)
-	raw, err := os.ReadFile(cfgPath)
+func loadConfig(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
 	if err != nil {
-		log.Fatalf("read config: %v", err)
+		return nil, fmt.Errorf("read config: %w", err)
 	}
 	var c Config
 	if err := json.Unmarshal(raw, &c); err != nil {
-		log.Fatalf("parse config: %v", err)
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if err := resolveSecrets(&c); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := validateAuth(c.Auth); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := validatePolicies(c.Policies); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := validateCache(c.Servers); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := validateGuards(c.Servers); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := validateRequired(c.Servers); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := validateBudgets(c.Servers); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := validateAliases(&c); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return &c, nil
+}
+func main() {
+	log.SetOutput(scrubWriter{os.Stderr})
+	if code, ok := runCommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+	abs, _ := filepath.Abs(cfgPath)
+	log.Printf("[bridge] loading %s", abs)
+	c, err := loadConfig(cfgPath)
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
 	agg := NewAggregator()
-	if err := agg.StartFromConfig(&c); err != nil {
+	// 审计日志打不开不影响服务，只是不记录调用
+	if agg.audit, err = openAuditLog(auditPath); err != nil {
+		log.Printf("[bridge] audit log disabled: %v", err)
+	}
+	srv := newHTTP(agg)
+	if err := srv.applyConfig(c); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if srv.tls, err = serverTLSConfig(c.Auth); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := agg.StartFromConfig(c); err != nil {
 		log.Fatalf("start backends: %v", err)
 	}
+	go agg.pingBackends()
 	defer agg.Close()
-	srv := newHTTP(agg)
+	if agg.audit != nil {
+		defer agg.audit.Close()
+	}
+	go watchConfig(cfgPath, func(c *Config) {
+		if err := srv.applyConfig(c); err != nil {
+			log.Printf("[bridge] reload: %v, keep running auth", err)
+		}
+		agg.Reload(c)
+	})
 	if err := srv.serve(bindAddr + ":" + bindPort); err != nil {
 		log.Fatalf("serve: %v", err)
 	}
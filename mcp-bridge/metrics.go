@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var durationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// 客户端可以发任意 method，超过这个数量的新 method 统一记为 "other"
+const maxMethodLabels = 64
+
+type callKey struct{ backend, tool string }
+type rejectKey struct{ backend, reason string }
+type callStats struct {
+	count   uint64
+	errors  uint64
+	sum     float64
+	buckets []uint64
+}
+
+// metrics 是 /metrics 暴露的计数器，格式为 Prometheus 文本格式
+type metrics struct {
+	mu       sync.Mutex
+	calls    map[callKey]*callStats
+	inflight map[string]int64
+	methods  map[string]uint64
+	rejected map[rejectKey]uint64
+	// truncated 是按 outputBudget 截断过的结果数
+	truncated map[callKey]uint64
+}
+
+var bridgeMetrics = &metrics{calls: map[callKey]*callStats{}, inflight: map[string]int64{}, methods: map[string]uint64{}, rejected: map[rejectKey]uint64{}, truncated: map[callKey]uint64{}}
+
+func (m *metrics) rpcRequest(method string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.methods[method]; !ok && len(m.methods) >= maxMethodLabels {
+		method = "other"
+	}
+	m.methods[method]++
+}
+
+// guardRejected 记录被限流或熔断直接拒绝的调用
+func (m *metrics) guardRejected(backend, reason string) {
+	m.mu.Lock()
+	m.rejected[rejectKey{backend, reason}]++
+	m.mu.Unlock()
+}
+
+func (m *metrics) resultTruncated(backend, tool string) {
+	m.mu.Lock()
+	m.truncated[callKey{backend, tool}]++
+	m.mu.Unlock()
+}
+
+// callStarted 记录一次 tools/call 开始，返回的函数在调用结束时记录耗时与结果
+func (m *metrics) callStarted(backend, tool string) func(failed bool) {
+	start := time.Now()
+	m.mu.Lock()
+	m.inflight[backend]++
+	m.mu.Unlock()
+	return func(failed bool) {
+		d := time.Since(start).Seconds()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.inflight[backend]--
+		k := callKey{backend, tool}
+		st := m.calls[k]
+		if st == nil {
+			st = &callStats{buckets: make([]uint64, len(durationBuckets))}
+			m.calls[k] = st
+		}
+		st.count++
+		st.sum += d
+		if failed {
+			st.errors++
+		}
+		for i, b := range durationBuckets {
+			if d <= b {
+				st.buckets[i]++
+			}
+		}
+	}
+}
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+func fmtFloat(f float64) string { return fmt.Sprintf("%g", f) }
+
+func (m *metrics) write(w *strings.Builder, status []backendStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]callKey, 0, len(m.calls))
+	for k := range m.calls {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].backend != keys[j].backend {
+			return keys[i].backend < keys[j].backend
+		}
+		return keys[i].tool < keys[j].tool
+	})
+
+	w.WriteString("# HELP mcp_bridge_tool_calls_total tools/call requests forwarded to backends.\n# TYPE mcp_bridge_tool_calls_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(w, "mcp_bridge_tool_calls_total{backend=\"%s\",tool=\"%s\"} %d\n", escapeLabel(k.backend), escapeLabel(k.tool), m.calls[k].count)
+	}
+	w.WriteString("# HELP mcp_bridge_tool_call_errors_total tools/call requests that returned an error.\n# TYPE mcp_bridge_tool_call_errors_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(w, "mcp_bridge_tool_call_errors_total{backend=\"%s\",tool=\"%s\"} %d\n", escapeLabel(k.backend), escapeLabel(k.tool), m.calls[k].errors)
+	}
+	w.WriteString("# HELP mcp_bridge_tool_call_duration_seconds tools/call latency.\n# TYPE mcp_bridge_tool_call_duration_seconds histogram\n")
+	for _, k := range keys {
+		st := m.calls[k]
+		lbl := fmt.Sprintf("backend=\"%s\",tool=\"%s\"", escapeLabel(k.backend), escapeLabel(k.tool))
+		for i, b := range durationBuckets {
+			fmt.Fprintf(w, "mcp_bridge_tool_call_duration_seconds_bucket{%s,le=\"%s\"} %d\n", lbl, fmtFloat(b), st.buckets[i])
+		}
+		fmt.Fprintf(w, "mcp_bridge_tool_call_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", lbl, st.count)
+		fmt.Fprintf(w, "mcp_bridge_tool_call_duration_seconds_sum{%s} %s\n", lbl, fmtFloat(st.sum))
+		fmt.Fprintf(w, "mcp_bridge_tool_call_duration_seconds_count{%s} %d\n", lbl, st.count)
+	}
+
+	truncated := make([]callKey, 0, len(m.truncated))
+	for k := range m.truncated {
+		truncated = append(truncated, k)
+	}
+	sort.Slice(truncated, func(i, j int) bool {
+		if truncated[i].backend != truncated[j].backend {
+			return truncated[i].backend < truncated[j].backend
+		}
+		return truncated[i].tool < truncated[j].tool
+	})
+	w.WriteString("# HELP mcp_bridge_tool_results_truncated_total Tool results cut down to the configured outputBudget.\n# TYPE mcp_bridge_tool_results_truncated_total counter\n")
+	for _, k := range truncated {
+		fmt.Fprintf(w, "mcp_bridge_tool_results_truncated_total{backend=\"%s\",tool=\"%s\"} %d\n", escapeLabel(k.backend), escapeLabel(k.tool), m.truncated[k])
+	}
+	w.WriteString("# HELP mcp_bridge_tool_calls_in_flight tools/call requests currently waiting on a backend.\n# TYPE mcp_bridge_tool_calls_in_flight gauge\n")
+	for _, st := range status {
+		fmt.Fprintf(w, "mcp_bridge_tool_calls_in_flight{backend=\"%s\"} %d\n", escapeLabel(st.Name), m.inflight[st.Name])
+	}
+
+	methods := make([]string, 0, len(m.methods))
+	for k := range m.methods {
+		methods = append(methods, k)
+	}
+	sort.Strings(methods)
+	w.WriteString("# HELP mcp_bridge_rpc_requests_total JSON-RPC messages received on /mcp by method.\n# TYPE mcp_bridge_rpc_requests_total counter\n")
+	for _, k := range methods {
+		fmt.Fprintf(w, "mcp_bridge_rpc_requests_total{method=\"%s\"} %d\n", escapeLabel(k), m.methods[k])
+	}
+
+	now := time.Now().Unix()
+	w.WriteString("# HELP mcp_bridge_backend_up Whether the backend is ready (1) or not (0).\n# TYPE mcp_bridge_backend_up gauge\n")
+	for _, st := range status {
+		up := 0
+		if st.State == stateReady {
+			up = 1
+		}
+		fmt.Fprintf(w, "mcp_bridge_backend_up{backend=\"%s\",transport=\"%s\"} %d\n", escapeLabel(st.Name), st.Transport, up)
+	}
+	w.WriteString("# HELP mcp_bridge_backend_restarts_total Backend restarts after an unexpected exit.\n# TYPE mcp_bridge_backend_restarts_total counter\n")
+	for _, st := range status {
+		fmt.Fprintf(w, "mcp_bridge_backend_restarts_total{backend=\"%s\"} %d\n", escapeLabel(st.Name), st.Restarts)
+	}
+	w.WriteString("# HELP mcp_bridge_backend_circuit_state Circuit breaker state: 0 closed, 1 half-open, 2 open.\n# TYPE mcp_bridge_backend_circuit_state gauge\n")
+	for _, st := range status {
+		if v, ok := map[string]int{breakerClosed: 0, breakerHalfOpen: 1, breakerOpen: 2}[st.Breaker]; ok {
+			fmt.Fprintf(w, "mcp_bridge_backend_circuit_state{backend=\"%s\"} %d\n", escapeLabel(st.Name), v)
+		}
+	}
+	rejected := make([]rejectKey, 0, len(m.rejected))
+	for k := range m.rejected {
+		rejected = append(rejected, k)
+	}
+	sort.Slice(rejected, func(i, j int) bool {
+		if rejected[i].backend != rejected[j].backend {
+			return rejected[i].backend < rejected[j].backend
+		}
+		return rejected[i].reason < rejected[j].reason
+	})
+	w.WriteString("# HELP mcp_bridge_backend_rejected_total Calls rejected before reaching the backend by rate limit or circuit breaker.\n# TYPE mcp_bridge_backend_rejected_total counter\n")
+	for _, k := range rejected {
+		fmt.Fprintf(w, "mcp_bridge_backend_rejected_total{backend=\"%s\",reason=\"%s\"} %d\n", escapeLabel(k.backend), k.reason, m.rejected[k])
+	}
+	w.WriteString("# HELP mcp_bridge_backend_uptime_seconds Seconds since the current backend instance (stdio child) became ready.\n# TYPE mcp_bridge_backend_uptime_seconds gauge\n")
+	for _, st := range status {
+		if st.State == stateReady && st.ReadySince > 0 {
+			fmt.Fprintf(w, "mcp_bridge_backend_uptime_seconds{backend=\"%s\",transport=\"%s\"} %d\n", escapeLabel(st.Name), st.Transport, now-st.ReadySince)
+		}
+	}
+}
+func (s *httpServer) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	var b strings.Builder
+	bridgeMetrics.write(&b, s.agg.Status())
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
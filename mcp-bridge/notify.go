@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// notifier 由能把服务端通知（没有 id 的消息）交给 bridge 的后端实现
+type notifier interface {
+	SetNotify(fn func(msg map[string]any))
+}
+
+// notifyHub 保存后端的通知回调，供各传输实现嵌入
+type notifyHub struct {
+	nm sync.Mutex
+	fn func(map[string]any)
+}
+
+func (n *notifyHub) SetNotify(fn func(map[string]any)) {
+	n.nm.Lock()
+	n.fn = fn
+	n.nm.Unlock()
+}
+func (n *notifyHub) emit(msg map[string]any) {
+	n.nm.Lock()
+	fn := n.fn
+	n.nm.Unlock()
+	if fn != nil {
+		fn(msg)
+	}
+}
+
+type metaKey struct{}
+
+// withMeta 把 tools/call 的 params._meta 带给后端（progressToken 等）
+func withMeta(ctx context.Context, meta map[string]any) context.Context {
+	if len(meta) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+func metaFrom(ctx context.Context) map[string]any {
+	m, _ := ctx.Value(metaKey{}).(map[string]any)
+	return m
+}
+
+// callParams 组装发给后端的 tools/call 参数；bridge 自己用的 _meta 键不转发
+func callParams(ctx context.Context, tool string, args map[string]any) map[string]any {
+	params := map[string]any{"name": tool, "arguments": args}
+	meta := map[string]any{}
+	for k, v := range metaFrom(ctx) {
+		if k != noCacheMeta {
+			meta[k] = v
+		}
+	}
+	if len(meta) > 0 {
+		params["_meta"] = meta
+	}
+	return params
+}
+
+// progressTarget 是一次调用的进度去向：客户端原始 token 和投递函数
+type progressTarget struct {
+	token   any
+	deliver func(msg map[string]any)
+}
+
+// progressRoute 把发给后端的 bridge 内唯一 token 映射回调用方，调用结束后删除；
+// 不同客户端可能使用相同的 token，所以不能直接透传
+type progressRoute struct {
+	mu  sync.Mutex
+	seq int64
+	m   map[string]progressTarget
+}
+
+func (p *progressRoute) add(orig any, deliver func(map[string]any)) (string, func()) {
+	p.mu.Lock()
+	p.seq++
+	tok := fmt.Sprintf("bridge-%d", p.seq)
+	p.m[tok] = progressTarget{token: orig, deliver: deliver}
+	p.mu.Unlock()
+	return tok, func() {
+		p.mu.Lock()
+		delete(p.m, tok)
+		p.mu.Unlock()
+	}
+}
+func (p *progressRoute) lookup(token any) (progressTarget, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t, ok := p.m[fmt.Sprint(token)]
+	return t, ok
+}
+
+// onNotify 处理后端 name 的实例 from 发来的通知：list_changed 触发重新拉取并广播，
+// progress 按 token 发给发起调用的会话，notifications/message 广播给所有会话，其余忽略
+func (a *Aggregator) onNotify(name string, from Backend, msg map[string]any) {
+	method, _ := msg["method"].(string)
+	params, _ := msg["params"].(map[string]any)
+	switch method {
+	case "notifications/tools/list_changed", "notifications/resources/list_changed", "notifications/prompts/list_changed":
+		go a.refresh(name, from, method)
+	case "notifications/progress":
+		if t, ok := a.progress.lookup(params["progressToken"]); ok {
+			t.deliver(map[string]any{"jsonrpc": "2.0", "method": method, "params": withField(params, "progressToken", t.token)})
+		}
+	case "notifications/message":
+		// 标明日志来自哪个后端
+		if params != nil && params["logger"] == nil {
+			msg = map[string]any{"jsonrpc": "2.0", "method": method, "params": withField(params, "logger", name)}
+		}
+		a.publish("", msg)
+	}
+}
+
+// refresh 重新拉取后端的工具、资源和 prompt，成功后通知所有会话；返回工具数。
+// from 不为 nil 时只在它仍是当前实例时刷新：还在启动的新实例 install 时会带上最新列表
+func (a *Aggregator) refresh(name string, from Backend, method string) (int, error) {
+	a.mu.RLock()
+	bk := a.backends[name]
+	a.mu.RUnlock()
+	switch {
+	case from != nil && from != bk:
+		return 0, errReplaced
+	case bk == nil:
+		return 0, errNotReady
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	tools, err := bk.ListTools(ctx)
+	if err != nil {
+		log.Printf("[%s] refresh after %s: %v", name, method, err)
+		return 0, err
+	}
+	cat := &catalog{tools: tools}
+	listExtras(ctx, bk, cat)
+	a.mu.Lock()
+	if a.backends[name] != bk {
+		a.mu.Unlock()
+		return 0, errReplaced
+	}
+	a.setCatalog(name, cat)
+	if sv := a.sup[name]; sv != nil {
+		sv.status.Tools = len(cat.tools)
+	}
+	a.mu.Unlock()
+	log.Printf("[%s] %s -> tools: %d, resources: %d, prompts: %d", name, method, len(cat.tools), len(cat.resources), len(cat.prompts))
+	a.publish("", map[string]any{"jsonrpc": "2.0", "method": method})
+	return len(cat.tools), nil
+}
+
+// catalogChanged 在后端上线、被替换或下线后通知所有会话重新拉取列表；
+// 资源和 prompt 只在相关后端声明过这些能力时通知
+func (a *Aggregator) catalogChanged(cats ...*catalog) {
+	a.publish("", map[string]any{"jsonrpc": "2.0", "method": "notifications/tools/list_changed"})
+	res, prompts := false, false
+	for _, c := range cats {
+		if c != nil {
+			res, prompts = res || c.hasRes, prompts || c.hasPrompt
+		}
+	}
+	if res {
+		a.publish("", map[string]any{"jsonrpc": "2.0", "method": "notifications/resources/list_changed"})
+	}
+	if prompts {
+		a.publish("", map[string]any{"jsonrpc": "2.0", "method": "notifications/prompts/list_changed"})
+	}
+}
+
+// publish 把消息发给指定会话（sid 为空时发给所有会话）的 GET /mcp 事件流
+func (a *Aggregator) publish(sid string, msg map[string]any) {
+	if a.sink != nil {
+		a.sink(sid, msg)
+	}
+}
+
+// 每个 GET 事件流的缓冲；客户端读得太慢时丢弃通知而不是阻塞后端
+const streamBuffer = 64
+
+func (se *session) attach() (chan map[string]any, func()) {
+	ch := make(chan map[string]any, streamBuffer)
+	se.mu.Lock()
+	if se.streams == nil {
+		se.streams = map[chan map[string]any]bool{}
+	}
+	se.streams[ch] = true
+	se.mu.Unlock()
+	return ch, func() {
+		se.mu.Lock()
+		delete(se.streams, ch)
+		// 事件流断开后从此刻开始计算空闲时间
+		se.lastSeen = time.Now()
+		se.mu.Unlock()
+	}
+}
+func (se *session) send(msg map[string]any) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	for ch := range se.streams {
+		select {
+		case ch <- msg:
+		default:
+			log.Printf("[bridge] session %s stream full, drop %v", se.ID, msg["method"])
+		}
+	}
+}
+
+// deliver 是 Aggregator 的通知出口
+func (st *sessionStore) deliver(sid string, msg map[string]any) {
+	st.mu.Lock()
+	var targets []*session
+	if sid != "" {
+		if se := st.m[sid]; se != nil {
+			targets = append(targets, se)
+		}
+	} else {
+		for _, se := range st.m {
+			targets = append(targets, se)
+		}
+	}
+	st.mu.Unlock()
+	for _, se := range targets {
+		se.send(msg)
+	}
+}
+
+// streamNotifications 在 GET /mcp 上持续输出会话的通知，并定时发送 keepalive
+func streamNotifications(ctx context.Context, se *session, write func(msg map[string]any), keepalive func()) {
+	var ch chan map[string]any
+	if se != nil {
+		var detach func()
+		ch, detach = se.attach()
+		defer detach()
+	}
+	ticker := time.NewTicker(25 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-ch:
+			write(msg)
+		case <-ticker.C:
+			keepalive()
+		}
+	}
+}
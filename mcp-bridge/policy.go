@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"path"
+)
+
+// ToolPolicy 用 glob 限制某个客户端身份可见、可调用的工具；deny 优先于 allow，
+// allow 为空表示除 deny 外全部允许
+type ToolPolicy struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// 未单独配置策略的身份使用 "*" 条目；两者都没有时不做限制
+const defaultPolicy = "*"
+
+const codeToolDenied = -32003
+
+func validatePolicies(ps map[string]ToolPolicy) error {
+	for id, p := range ps {
+		for _, g := range append(append([]string(nil), p.Allow...), p.Deny...) {
+			if _, err := path.Match(g, ""); err != nil {
+				return fmt.Errorf("policies.%s: bad pattern %q", id, g)
+			}
+		}
+	}
+	return nil
+}
+func matchAny(globs []string, names ...string) bool {
+	for _, g := range globs {
+		for _, n := range names {
+			if ok, _ := path.Match(g, n); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+func (p ToolPolicy) permits(names ...string) bool {
+	if matchAny(p.Deny, names...) {
+		return false
+	}
+	return len(p.Allow) == 0 || matchAny(p.Allow, names...)
+}
+
+// allowed 调用方需持有 a.mu 读锁
+func (a *Aggregator) allowed(client string, names ...string) bool {
+	p, ok := a.policies[client]
+	if !ok {
+		if p, ok = a.policies[defaultPolicy]; !ok {
+			return true
+		}
+	}
+	return p.permits(names...)
+}
+func (a *Aggregator) applyPolicies(ps map[string]ToolPolicy) {
+	a.mu.Lock()
+	a.policies = ps
+	a.mu.Unlock()
+}
@@ -52,7 +52,8 @@ sudo systemctl status "$SERVICE_NAME" --no-pager || true
 echo "🏥 健康检查:"
 if curl -sS http://127.0.0.1:"$PORT"/healthz >/dev/null 2>&1; then
     echo "✅ mcp-bridge 服务运行正常！"
-    curl -sS http://127.0.0.1:"$PORT"/healthz | jq . 2>/dev/null || curl -sS http://127.0.0.1:"$PORT"/healthz
+    # healthz 只表示进程存活，后端状态看 readyz（required 后端未就绪时返回 503）
+    curl -sS http://127.0.0.1:"$PORT"/readyz | jq . 2>/dev/null || curl -sS http://127.0.0.1:"$PORT"/readyz
 else
     echo "❌ mcp-bridge 服务健康检查失败！"
     echo "📋 查看日志:"
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+var configPoll = getenvDur("CONFIG_POLL", 5*time.Second)
+
+// Reload 对比新旧 mcpServers，只启动/停止/重启有变化的后端；
+// 未变化的后端及其在途调用不受影响
+func (a *Aggregator) Reload(c *Config) {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	a.applyPolicies(c.Policies)
+	a.applyAliases(c)
+	want := map[string]string{}
+	for raw, sp := range c.Servers {
+		if !sp.Disabled {
+			want[sanitizeName(raw)] = raw
+		}
+	}
+	a.mu.RLock()
+	running := make(map[string]*supervised, len(a.sup))
+	for name, sv := range a.sup {
+		running[name] = sv
+	}
+	a.mu.RUnlock()
+	for name, sv := range running {
+		if _, ok := want[name]; !ok {
+			log.Printf("[%s] removed from config -> stop", sv.raw)
+			a.stop(name)
+		}
+	}
+	a.mu.Lock()
+	for name := range a.parked {
+		if _, ok := want[name]; !ok {
+			delete(a.parked, name)
+		}
+	}
+	a.mu.Unlock()
+	for name, raw := range want {
+		sp := c.Servers[raw]
+		sv := running[name]
+		switch {
+		case a.park(name, raw, sp):
+			log.Printf("[%s] disabled via admin API -> skip", raw)
+		case sv == nil:
+			log.Printf("[%s] added to config -> start", raw)
+			a.start(raw, name, sp)
+		case sv.raw != raw || !reflect.DeepEqual(sv.spec, sp):
+			log.Printf("[%s] changed in config -> restart", raw)
+			a.start(raw, name, sp)
+		}
+	}
+}
+
+// stop 摘除后端及其工具；后端本身延迟关闭，让在途调用自然结束
+func (a *Aggregator) stop(name string) {
+	a.mu.Lock()
+	sv := a.sup[name]
+	bk, cat := a.backends[name], a.catalogs[name]
+	delete(a.sup, name)
+	delete(a.backends, name)
+	a.dropTools(name)
+	a.mu.Unlock()
+	a.setCaches(name, nil)
+	if cat != nil {
+		a.catalogChanged(cat)
+	}
+	if sv != nil {
+		sv.halt()
+	}
+	if bk != nil {
+		go closeAfterDrain(bk)
+	}
+}
+
+// closeAfterDrain 等待一个 BACKEND_TIMEOUT（在途调用的最长耗时）后再关闭后端
+func closeAfterDrain(bk Backend) {
+	time.Sleep(timeout)
+	_ = bk.Close()
+}
+
+// watchConfig 在收到 SIGHUP 或配置文件内容变化时重新加载
+func watchConfig(path string, apply func(*Config)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	last, _ := os.ReadFile(path)
+	var tick <-chan time.Time
+	if configPoll > 0 {
+		t := time.NewTicker(configPoll)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		force := false
+		select {
+		case <-hup:
+			force = true
+		case <-tick:
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("[bridge] reload: %v", err)
+			continue
+		}
+		if !force && bytes.Equal(raw, last) {
+			continue
+		}
+		c, err := loadConfig(path)
+		if err != nil {
+			log.Printf("[bridge] reload: %v, keep running config", err)
+			continue
+		}
+		last = raw
+		log.Printf("[bridge] reloading %s", path)
+		apply(c)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// catalog 是后端启动时列出的工具、资源和 prompt。资源 URI 导出为 "<命名空间>+<原 URI>"，
+// prompt 与工具一样导出为 "<命名空间>.<原名>"；命名空间默认是后端名
+type catalog struct {
+	tools     []ToolItem
+	resources []map[string]any
+	templates []map[string]any
+	prompts   []map[string]any
+	hasRes    bool
+	hasPrompt bool
+}
+
+// codeResourceNotFound 是 MCP 规范中 resources/read 找不到资源时的错误码
+const codeResourceNotFound = -32002
+
+// listExtras 按后端声明的 capabilities 拉取资源和 prompt；失败只记日志，不影响工具
+func listExtras(ctx context.Context, bk Backend, cat *catalog) {
+	caps := bk.Capabilities()
+	if _, ok := caps["resources"]; ok {
+		cat.hasRes = true
+		cat.resources = listAll(ctx, bk, "resources/list", "resources")
+		cat.templates = listAll(ctx, bk, "resources/templates/list", "resourceTemplates")
+	}
+	if _, ok := caps["prompts"]; ok {
+		cat.hasPrompt = true
+		cat.prompts = listAll(ctx, bk, "prompts/list", "prompts")
+	}
+}
+
+// listAll 跟随 nextCursor 取完所有分页
+func listAll(ctx context.Context, bk Backend, method, key string) []map[string]any {
+	var out []map[string]any
+	params := map[string]any{}
+	for {
+		res, err := bk.Request(ctx, method, params)
+		if err != nil {
+			log.Printf("[%s] %s: %v", bk.Name(), method, err)
+			return out
+		}
+		items, _ := res[key].([]any)
+		for _, it := range items {
+			if m, ok := it.(map[string]any); ok {
+				out = append(out, m)
+			}
+		}
+		cursor, _ := res["nextCursor"].(string)
+		if cursor == "" {
+			return out
+		}
+		params = map[string]any{"cursor": cursor}
+	}
+}
+func exportURI(ns, uri string) string { return ns + "+" + uri }
+
+// splitURI 把导出的 URI 拆回命名空间和原 URI；命名空间不含 "+"
+func splitURI(uri string) (ns, orig string, ok bool) {
+	i := strings.Index(uri, "+")
+	if i <= 0 {
+		return "", "", false
+	}
+	return uri[:i], uri[i+1:], true
+}
+func withField(m map[string]any, k string, v any) map[string]any {
+	out := make(map[string]any, len(m))
+	for kk, vv := range m {
+		out[kk] = vv
+	}
+	out[k] = v
+	return out
+}
+
+// Capabilities 是 bridge 在 initialize 中声明的能力，只包含当前确实可用的部分
+func (a *Aggregator) Capabilities() map[string]any {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	// 后端通知 list_changed 时 bridge 会重新拉取并转发
+	changed := map[string]any{"listChanged": true}
+	caps := map[string]any{"tools": changed}
+	for _, cat := range a.catalogs {
+		if cat.hasRes {
+			caps["resources"] = changed
+		}
+		if cat.hasPrompt {
+			caps["prompts"] = changed
+		}
+	}
+	return caps
+}
+
+// exported 按 fn 转换所有后端的某类条目（fn 收到后端的命名空间），并按 key 字段排序
+func (a *Aggregator) exported(key string, fn func(ns string, cat *catalog) []map[string]any) []map[string]any {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := []map[string]any{}
+	for srv, cat := range a.catalogs {
+		out = append(out, fn(a.namespace(srv), cat)...)
+	}
+	sort.Slice(out, func(i, j int) bool { return fmt.Sprint(out[i][key]) < fmt.Sprint(out[j][key]) })
+	return out
+}
+func (a *Aggregator) ListResources() []map[string]any {
+	return a.exported("uri", func(ns string, cat *catalog) (out []map[string]any) {
+		for _, r := range cat.resources {
+			if uri, ok := r["uri"].(string); ok {
+				out = append(out, withField(r, "uri", exportURI(ns, uri)))
+			}
+		}
+		return out
+	})
+}
+func (a *Aggregator) ListResourceTemplates() []map[string]any {
+	return a.exported("uriTemplate", func(ns string, cat *catalog) (out []map[string]any) {
+		for _, t := range cat.templates {
+			if tmpl, ok := t["uriTemplate"].(string); ok {
+				out = append(out, withField(t, "uriTemplate", exportURI(ns, tmpl)))
+			}
+		}
+		return out
+	})
+}
+func (a *Aggregator) ListPrompts() []map[string]any {
+	return a.exported("name", func(ns string, cat *catalog) (out []map[string]any) {
+		for _, p := range cat.prompts {
+			if name, ok := p["name"].(string); ok {
+				out = append(out, withField(p, "name", ns+"."+name))
+			}
+		}
+		return out
+	})
+}
+
+// ReadResource 把 resources/read 转给 URI 前缀对应的后端，并把返回内容中的 URI 改回导出形式
+func (a *Aggregator) ReadResource(ctx context.Context, uri string) (map[string]any, error) {
+	ns, orig, ok := splitURI(uri)
+	a.mu.RLock()
+	srv := a.backendOf(ns)
+	bk, cat := a.backends[srv], a.catalogs[srv]
+	a.mu.RUnlock()
+	if !ok || bk == nil || cat == nil || !cat.hasRes {
+		return nil, &rpcErr{Code: codeResourceNotFound, Message: "Resource not found: " + uri}
+	}
+	res, err := bk.Request(ctx, "resources/read", map[string]any{"uri": orig})
+	if err != nil {
+		return nil, err
+	}
+	if contents, ok := res["contents"].([]any); ok {
+		for i, c := range contents {
+			if m, ok := c.(map[string]any); ok {
+				if u, ok := m["uri"].(string); ok {
+					contents[i] = withField(m, "uri", exportURI(ns, u))
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// GetPrompt 按导出名找到后端和原始 prompt 名
+func (a *Aggregator) GetPrompt(ctx context.Context, name string, args map[string]any) (map[string]any, error) {
+	var bk Backend
+	var orig string
+	a.mu.RLock()
+	if i := strings.Index(name, "."); i > 0 {
+		srv := a.backendOf(name[:i])
+		if cat := a.catalogs[srv]; cat != nil {
+			for _, p := range cat.prompts {
+				if p["name"] == name[i+1:] {
+					bk, orig = a.backends[srv], name[i+1:]
+				}
+			}
+		}
+	}
+	a.mu.RUnlock()
+	if bk == nil {
+		return nil, &rpcErr{Code: -32602, Message: "unknown prompt: " + name}
+	}
+	params := map[string]any{"name": orig}
+	if args != nil {
+		params["arguments"] = args
+	}
+	return bk.Request(ctx, "prompts/get", params)
+}
@@ -50,7 +50,7 @@ sudo chown -R "$(whoami):$(whoami)" "$PROJECT_DIR"
 if [ "$(pwd)" != "$PROJECT_DIR" ]; then
     echo "   复制文件到项目目录..."
     install -m 0644 ./go.mod "$PROJECT_DIR/go.mod"
-    install -m 0644 ./main.go "$PROJECT_DIR/main.go"
+    install -m 0644 ./*.go "$PROJECT_DIR/"
     install -m 0644 ./mcp.json "$PROJECT_DIR/mcp.json"
     
     # 复制wrapper脚本
@@ -93,6 +93,7 @@ Type=simple
 WorkingDirectory=$PROJECT_DIR
 EnvironmentFile=-/etc/default/mcp-bridge
 ExecStart=/usr/local/bin/mcp-bridge
+ExecReload=/bin/kill -HUP \$MAINPID
 Restart=on-failure
 RestartSec=2s
 User=root
@@ -125,7 +126,8 @@ sudo systemctl status "$SERVICE_NAME" --no-pager || true
 echo "🏥 健康检查:"
 if curl -sS http://127.0.0.1:"$PORT"/healthz >/dev/null 2>&1; then
     echo "✅ mcp-bridge 服务运行正常！"
-    curl -sS http://127.0.0.1:"$PORT"/healthz | jq . 2>/dev/null || curl -sS http://127.0.0.1:"$PORT"/healthz
+    # healthz 只表示进程存活，后端状态看 readyz（required 后端未就绪时返回 503）
+    curl -sS http://127.0.0.1:"$PORT"/readyz | jq . 2>/dev/null || curl -sS http://127.0.0.1:"$PORT"/readyz
 else
     echo "❌ mcp-bridge 服务健康检查失败！"
     echo "📋 查看日志:"
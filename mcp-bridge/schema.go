@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// validateArgs 为 false 时跳过参数校验，原样转发给后端
+var validateArgs = getenvBool("VALIDATE_ARGS", true)
+
+// fieldError 指出哪个参数不符合 inputSchema；Field 用点号和 [i] 表示嵌套位置
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// 最多报告这么多条错误，避免巨大的参数刷屏
+const maxFieldErrors = 10
+
+// checkArgs 用工具的 inputSchema 校验参数，只实现工具描述里常用的 JSON Schema 子集
+// （type、required、properties、additionalProperties、items、enum、const、
+// 数值/长度/个数范围、pattern、allOf/anyOf/oneOf），不认识的关键字忽略
+func checkArgs(tool string, schema map[string]any, args map[string]any) error {
+	if schema == nil {
+		return nil
+	}
+	var v any = args
+	if args == nil {
+		v = map[string]any{}
+	}
+	var errs []fieldError
+	checkValue(schema, v, "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Field + ": " + e.Message
+		if e.Field == "" {
+			msgs[i] = e.Message
+		}
+	}
+	return &rpcErr{
+		Code:    -32602,
+		Message: fmt.Sprintf("invalid arguments for %s: %s", tool, strings.Join(msgs, "; ")),
+		Data:    map[string]any{"tool": tool, "errors": errs},
+	}
+}
+func addErr(errs *[]fieldError, field, format string, a ...any) {
+	if len(*errs) < maxFieldErrors {
+		*errs = append(*errs, fieldError{Field: field, Message: fmt.Sprintf(format, a...)})
+	}
+}
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+func checkValue(schema map[string]any, v any, field string, errs *[]fieldError) {
+	if t, ok := schema["type"]; ok && !typeMatches(t, v) {
+		addErr(errs, field, "expected %s, got %s", typeList(t), jsonType(v))
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, v) {
+		addErr(errs, field, "must be one of %s", fmtValues(enum))
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, v) {
+		addErr(errs, field, "must be %v", c)
+	}
+	switch x := v.(type) {
+	case string:
+		n := float64(len([]rune(x)))
+		if m, ok := num(schema["minLength"]); ok && n < m {
+			addErr(errs, field, "must be at least %g characters", m)
+		}
+		if m, ok := num(schema["maxLength"]); ok && n > m {
+			addErr(errs, field, "must be at most %g characters", m)
+		}
+		if p, ok := schema["pattern"].(string); ok {
+			if re := compilePattern(p); re != nil && !re.MatchString(x) {
+				addErr(errs, field, "does not match pattern %q", p)
+			}
+		}
+	case float64:
+		if m, ok := num(schema["minimum"]); ok && x < m {
+			addErr(errs, field, "must be >= %g", m)
+		}
+		if m, ok := num(schema["maximum"]); ok && x > m {
+			addErr(errs, field, "must be <= %g", m)
+		}
+		if m, ok := num(schema["exclusiveMinimum"]); ok && x <= m {
+			addErr(errs, field, "must be > %g", m)
+		}
+		if m, ok := num(schema["exclusiveMaximum"]); ok && x >= m {
+			addErr(errs, field, "must be < %g", m)
+		}
+	case []any:
+		n := float64(len(x))
+		if m, ok := num(schema["minItems"]); ok && n < m {
+			addErr(errs, field, "must have at least %g items", m)
+		}
+		if m, ok := num(schema["maxItems"]); ok && n > m {
+			addErr(errs, field, "must have at most %g items", m)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, it := range x {
+				checkValue(items, it, fmt.Sprintf("%s[%d]", field, i), errs)
+			}
+		}
+	case map[string]any:
+		checkObject(schema, x, field, errs)
+	}
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, s := range all {
+			if sm, ok := s.(map[string]any); ok {
+				checkValue(sm, v, field, errs)
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok && countMatches(anyOf, v) == 0 {
+		addErr(errs, field, "does not match any of the allowed schemas")
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok && countMatches(oneOf, v) != 1 {
+		addErr(errs, field, "must match exactly one of the allowed schemas")
+	}
+}
+func checkObject(schema map[string]any, obj map[string]any, field string, errs *[]fieldError) {
+	props, _ := schema["properties"].(map[string]any)
+	if req, ok := schema["required"].([]any); ok {
+		for _, r := range req {
+			if name, ok := r.(string); ok {
+				if _, present := obj[name]; !present {
+					addErr(errs, joinField(field, name), "required property is missing")
+				}
+			}
+		}
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if ps, ok := props[k].(map[string]any); ok {
+			checkValue(ps, obj[k], joinField(field, k), errs)
+			continue
+		}
+		switch ap := schema["additionalProperties"].(type) {
+		case bool:
+			if !ap {
+				addErr(errs, joinField(field, k), "unknown property (allowed: %s)", strings.Join(sortedKeys(props), ", "))
+			}
+		case map[string]any:
+			checkValue(ap, obj[k], joinField(field, k), errs)
+		}
+	}
+}
+func countMatches(schemas []any, v any) int {
+	n := 0
+	for _, s := range schemas {
+		if sm, ok := s.(map[string]any); ok {
+			var errs []fieldError
+			checkValue(sm, v, "", &errs)
+			if len(errs) == 0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+func typeMatches(t any, v any) bool {
+	switch tt := t.(type) {
+	case string:
+		return isType(tt, v)
+	case []any:
+		for _, x := range tt {
+			if s, ok := x.(string); ok && isType(s, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+func isType(t string, v any) bool {
+	switch t {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	default:
+		return jsonType(v) == t
+	}
+}
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+func typeList(t any) string {
+	if l, ok := t.([]any); ok {
+		parts := make([]string, len(l))
+		for i, x := range l {
+			parts[i] = fmt.Sprint(x)
+		}
+		return strings.Join(parts, " or ")
+	}
+	return fmt.Sprint(t)
+}
+func num(v any) (float64, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
+func containsValue(list []any, v any) bool {
+	for _, x := range list {
+		if reflect.DeepEqual(x, v) {
+			return true
+		}
+	}
+	return false
+}
+func fmtValues(list []any) string {
+	parts := make([]string, len(list))
+	for i, x := range list {
+		parts[i] = fmt.Sprintf("%q", fmt.Sprint(x))
+	}
+	return strings.Join(parts, ", ")
+}
+func sortedKeys(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+var patternCache sync.Map
+
+// compilePattern 缓存编译结果；Go 不支持的正则写法（如后行断言）返回 nil，不做校验
+func compilePattern(p string) *regexp.Regexp {
+	if re, ok := patternCache.Load(p); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil
+	}
+	patternCache.Store(p, re)
+	return re
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// 与 Elasticsearch search 工具类似的 inputSchema，覆盖嵌套对象和数组
+const searchSchema = `{
+  "type": "object",
+  "properties": {
+    "index": {"type": "string", "minLength": 1},
+    "size": {"type": "integer", "minimum": 1, "maximum": 100},
+    "order": {"type": "string", "enum": ["asc", "desc"]},
+    "query": {
+      "type": "object",
+      "properties": {
+        "field": {"type": "string", "pattern": "^[a-z_.]+$"},
+        "range": {
+          "type": "object",
+          "properties": {"gte": {"type": "string"}, "lte": {"type": "string"}},
+          "required": ["gte"],
+          "additionalProperties": false
+        }
+      },
+      "required": ["field"]
+    },
+    "fields": {"type": "array", "items": {"type": "string"}, "maxItems": 3}
+  },
+  "required": ["index"]
+}`
+
+func TestCheckArgs(t *testing.T) {
+	cases := []struct {
+		name   string
+		args   string
+		fields []string // 出错的字段，为空表示校验通过
+	}{
+		{"valid", `{"index":"logs-*","size":10,"order":"asc","query":{"field":"host.name","range":{"gte":"now-1h"}},"fields":["a"]}`, nil},
+		{"only required", `{"index":"logs-*"}`, nil},
+		{"missing required", `{}`, []string{"index"}},
+		{"wrong type", `{"index":5}`, []string{"index"}},
+		{"integer with fraction", `{"index":"x","size":1.5}`, []string{"size"}},
+		{"out of range", `{"index":"x","size":0}`, []string{"size"}},
+		{"enum", `{"index":"x","order":"up"}`, []string{"order"}},
+		{"min length", `{"index":""}`, []string{"index"}},
+		{"nested required", `{"index":"x","query":{}}`, []string{"query.field"}},
+		{"nested pattern", `{"index":"x","query":{"field":"Host Name"}}`, []string{"query.field"}},
+		{"deep nested required", `{"index":"x","query":{"field":"a","range":{"lte":"now"}}}`, []string{"query.range.gte"}},
+		{"additional properties", `{"index":"x","query":{"field":"a","range":{"gte":"now","from":"x"}}}`, []string{"query.range.from"}},
+		{"array items", `{"index":"x","fields":["a",2]}`, []string{"fields[1]"}},
+		{"max items", `{"index":"x","fields":["a","b","c","d"]}`, []string{"fields"}},
+		{"several errors", `{"size":500,"order":"up"}`, []string{"index", "order", "size"}},
+	}
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(searchSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var args map[string]any
+			if err := json.Unmarshal([]byte(tc.args), &args); err != nil {
+				t.Fatal(err)
+			}
+			var fields []string
+			if err := checkArgs("es.search", schema, args); err != nil {
+				var re *rpcErr
+				if !errors.As(err, &re) || re.Code != -32602 {
+					t.Fatalf("error %v, want rpcErr -32602", err)
+				}
+				for _, fe := range re.Data.(map[string]any)["errors"].([]fieldError) {
+					fields = append(fields, fe.Field)
+				}
+			}
+			if !reflect.DeepEqual(fields, tc.fields) {
+				t.Fatalf("error fields %q, want %q", fields, tc.fields)
+			}
+		})
+	}
+}
+func TestCheckArgsCombinators(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		args   string
+		ok     bool
+	}{
+		{"anyOf match", `{"properties":{"t":{"anyOf":[{"type":"string"},{"type":"number"}]}}}`, `{"t":1}`, true},
+		{"anyOf no match", `{"properties":{"t":{"anyOf":[{"type":"string"},{"type":"number"}]}}}`, `{"t":true}`, false},
+		{"oneOf two matches", `{"properties":{"t":{"oneOf":[{"type":"number"},{"type":"integer"}]}}}`, `{"t":1}`, false},
+		{"type list", `{"properties":{"t":{"type":["string","null"]}}}`, `{"t":null}`, true},
+		{"const", `{"properties":{"t":{"const":"v1"}}}`, `{"t":"v2"}`, false},
+		{"unsupported pattern ignored", `{"properties":{"t":{"type":"string","pattern":"(?<=a)b"}}}`, `{"t":"x"}`, true},
+		{"nil schema", ``, `{"t":1}`, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var schema, args map[string]any
+			if tc.schema != "" {
+				if err := json.Unmarshal([]byte(tc.schema), &schema); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := json.Unmarshal([]byte(tc.args), &args); err != nil {
+				t.Fatal(err)
+			}
+			if err := checkArgs("t", schema, args); (err == nil) != tc.ok {
+				t.Fatalf("checkArgs = %v, want ok=%v", err, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretTimeout 限制 exec: 引用的命令运行时间
+var secretTimeout = getenvDur("SECRET_EXEC_TIMEOUT", 10*time.Second)
+
+// 子进程默认只继承这些与凭据无关的变量，其余的需要在 envAllow 中列出
+var baseEnv = []string{"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "LC_*", "TZ", "TMPDIR"}
+
+// 比这更短的值不登记为密钥，否则日志里的普通字符也会被替换
+const minSecretLen = 4
+
+var sensitiveHeader = regexp.MustCompile(`(?i)^(authorization|proxy-authorization|cookie)$`)
+
+// secretSet 记录配置中解析出的敏感值，日志输出前把它们替换为 ***
+type secretSet struct {
+	mu   sync.RWMutex
+	vals map[string]bool
+	rep  *strings.Replacer
+}
+
+var secrets = &secretSet{vals: map[string]bool{}}
+
+func (s *secretSet) add(v string) {
+	v = strings.TrimSpace(v)
+	if len(v) < minSecretLen {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.vals[v] {
+		return
+	}
+	s.vals[v] = true
+	// 长的先替换，避免一个密钥是另一个的子串时只替换一半
+	vals := make([]string, 0, len(s.vals))
+	for x := range s.vals {
+		vals = append(vals, x)
+	}
+	sort.Slice(vals, func(i, j int) bool { return len(vals[i]) > len(vals[j]) })
+	pairs := make([]string, 0, 2*len(vals))
+	for _, x := range vals {
+		pairs = append(pairs, x, "***")
+	}
+	s.rep = strings.NewReplacer(pairs...)
+}
+func (s *secretSet) scrub(v string) string {
+	s.mu.RLock()
+	rep := s.rep
+	s.mu.RUnlock()
+	if rep == nil {
+		return v
+	}
+	return rep.Replace(v)
+}
+
+// scrubWriter 是 log 的输出，所有日志（包括子进程 stderr 和错误信息）都经过它
+type scrubWriter struct{ w io.Writer }
+
+func (s scrubWriter) Write(p []byte) (int, error) {
+	if _, err := s.w.Write([]byte(secrets.scrub(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// resolveSecrets 在加载配置时展开引用：env、headers、auth 的值支持 ${VAR}、file:<路径> 和
+// exec:<命令>，args 和 url 只支持 ${VAR}。敏感位置的最终值登记到 secrets；错误信息不包含值
+func resolveSecrets(c *Config) error {
+	for raw, sp := range c.Servers {
+		where := "mcpServers." + raw
+		for _, g := range sp.EnvAllow {
+			if _, err := path.Match(g, ""); err != nil {
+				return fmt.Errorf("%s.envAllow: bad pattern %q", where, g)
+			}
+		}
+		if sp.Disabled {
+			continue
+		}
+		var err error
+		if sp.Env, err = resolveMap(sp.Env, where+".env", sensitiveKey.MatchString); err != nil {
+			return err
+		}
+		sensitive := func(k string) bool { return sensitiveKey.MatchString(k) || sensitiveHeader.MatchString(k) }
+		if sp.Headers, err = resolveMap(sp.Headers, where+".headers", sensitive); err != nil {
+			return err
+		}
+		if len(sp.Args) > 0 {
+			args := make([]string, len(sp.Args))
+			for i, a := range sp.Args {
+				if args[i], err = expandVars(a, false); err != nil {
+					return fmt.Errorf("%s.args[%d]: %w", where, i, err)
+				}
+			}
+			sp.Args = args
+		}
+		if sp.URL, err = expandVars(sp.URL, false); err != nil {
+			return fmt.Errorf("%s.url: %w", where, err)
+		}
+		c.Servers[raw] = sp
+	}
+	if au := c.Auth; au != nil {
+		var err error
+		if au.HMACSecret, err = resolveRef(au.HMACSecret, true); err != nil {
+			return fmt.Errorf("auth.hmacSecret: %w", err)
+		}
+		for i := range au.Keys {
+			if au.Keys[i].Key, err = resolveRef(au.Keys[i].Key, true); err != nil {
+				return fmt.Errorf("auth.keys[%d].key: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+func resolveMap(m map[string]string, where string, sensitive func(string) bool) (map[string]string, error) {
+	if len(m) == 0 {
+		return m, nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		r, err := resolveRef(v, sensitive(k))
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", where, k, err)
+		}
+		out[k] = r
+	}
+	return out, nil
+}
+
+// resolveRef 解析一个值；file: 和 exec: 的结果总是当作密钥，去掉末尾换行
+func resolveRef(v string, sensitive bool) (string, error) {
+	var out string
+	switch {
+	case strings.HasPrefix(v, "file:"):
+		b, err := os.ReadFile(strings.TrimPrefix(v, "file:"))
+		if err != nil {
+			return "", err
+		}
+		out, sensitive = strings.TrimRight(string(b), "\r\n"), true
+	case strings.HasPrefix(v, "exec:"):
+		ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+		defer cancel()
+		// 不带上命令的 stderr，它可能包含密钥的一部分
+		b, err := exec.CommandContext(ctx, "/bin/sh", "-c", strings.TrimPrefix(v, "exec:")).Output()
+		if err != nil {
+			return "", fmt.Errorf("exec: %w", err)
+		}
+		out, sensitive = strings.TrimRight(string(b), "\r\n"), true
+	default:
+		var err error
+		if out, err = expandVars(v, sensitive); err != nil {
+			return "", err
+		}
+	}
+	if sensitive {
+		secrets.add(out)
+	}
+	return out, nil
+}
+
+var varRef = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// expandVars 展开 ${VAR} 和 ${VAR:-默认值}，$${ 表示字面的 ${；未设置且没有默认值的变量是错误。
+// 变量名看起来敏感（或 sensitive 为 true）时，展开出的值登记为密钥
+func expandVars(v string, sensitive bool) (string, error) {
+	var err error
+	out := varRef.ReplaceAllStringFunc(v, func(m string) string {
+		if m == "$${" {
+			return "${"
+		}
+		sub := varRef.FindStringSubmatch(m)
+		val, ok := os.LookupEnv(sub[1])
+		if !ok {
+			if sub[2] == "" {
+				if err == nil {
+					err = fmt.Errorf("variable %s is not set", sub[1])
+				}
+				return ""
+			}
+			val = strings.TrimPrefix(sub[2], ":-")
+		}
+		if sensitive || sensitiveKey.MatchString(sub[1]) {
+			secrets.add(val)
+		}
+		return val
+	})
+	return out, err
+}
+
+// childEnv 是 stdio 子进程的环境：baseEnv 和 envAllow 匹配的 bridge 环境变量，加上配置中的 env。
+// envAllow 为 ["*"] 时继承全部环境变量
+func childEnv(sp SrvSpec) []string {
+	allow := append(append([]string(nil), baseEnv...), sp.EnvAllow...)
+	var env []string
+	for _, kv := range os.Environ() {
+		if k, _, ok := strings.Cut(kv, "="); ok && matchAny(allow, k) {
+			env = append(env, kv)
+		}
+	}
+	keys := make([]string, 0, len(sp.Env))
+	for k := range sp.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+sp.Env[k])
+	}
+	return env
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	sessionIdleTTL = getenvDur("SESSION_IDLE_TTL", 30*time.Minute)
+	// sessionRequired 为 false 时兼容不做握手的老客户端：不带会话 id 也可以调用，也不检查 initialized
+	sessionRequired = getenvBool("SESSION_REQUIRED", true)
+)
+
+// 按新旧顺序排列，客户端请求的版本不在列表中时回退到第一个
+var supportedProtocols = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+func negotiateProtocol(requested string) string {
+	for _, v := range supportedProtocols {
+		if v == requested {
+			return v
+		}
+	}
+	return supportedProtocols[0]
+}
+func protocolSupported(v string) bool {
+	for _, p := range supportedProtocols {
+		if p == v {
+			return true
+		}
+	}
+	return false
+}
+
+// session 是 /mcp 上一个客户端的会话状态，initialize 时创建
+type session struct {
+	ID              string
+	Client          string
+	ProtocolVersion string
+	ClientInfo      map[string]any
+	Capabilities    map[string]any
+	Created         time.Time
+
+	mu          sync.Mutex
+	initialized bool
+	lastSeen    time.Time
+	streams     map[chan map[string]any]bool
+	inflight    map[string]context.CancelFunc
+}
+
+func (se *session) touch() {
+	se.mu.Lock()
+	se.lastSeen = time.Now()
+	se.mu.Unlock()
+}
+func (se *session) markInitialized() {
+	se.mu.Lock()
+	se.initialized = true
+	se.mu.Unlock()
+}
+func (se *session) Initialized() bool {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	return se.initialized
+}
+
+// idleSince 返回最近一次活动的时间；开着 GET 事件流的会话不算空闲
+func (se *session) idleSince() time.Time {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	if len(se.streams) > 0 {
+		return time.Now()
+	}
+	return se.lastSeen
+}
+
+type sessionStore struct {
+	mu  sync.Mutex
+	m   map[string]*session
+	ttl time.Duration
+}
+
+func newSessionStore(ttl time.Duration) *sessionStore {
+	st := &sessionStore{m: map[string]*session{}, ttl: ttl}
+	if ttl > 0 {
+		go st.janitor()
+	}
+	return st
+}
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+func (st *sessionStore) create(client, protocol string, clientInfo, caps map[string]any) *session {
+	now := time.Now()
+	se := &session{ID: newSessionID(), Client: client, ProtocolVersion: protocol, ClientInfo: clientInfo, Capabilities: caps, Created: now, lastSeen: now}
+	st.mu.Lock()
+	st.m[se.ID] = se
+	st.mu.Unlock()
+	return se
+}
+func (st *sessionStore) get(id string) (*session, bool) {
+	st.mu.Lock()
+	se, ok := st.m[id]
+	st.mu.Unlock()
+	if ok {
+		se.touch()
+	}
+	return se, ok
+}
+func (st *sessionStore) remove(id string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.m[id]; !ok {
+		return false
+	}
+	delete(st.m, id)
+	return true
+}
+func (st *sessionStore) janitor() {
+	t := time.NewTicker(st.ttl / 4)
+	defer t.Stop()
+	for range t.C {
+		cutoff := time.Now().Add(-st.ttl)
+		st.mu.Lock()
+		for id, se := range st.m {
+			if se.idleSince().Before(cutoff) {
+				delete(st.m, id)
+				log.Printf("[bridge] session %s expired", id)
+			}
+		}
+		st.mu.Unlock()
+	}
+}
+
+// lookup 校验请求携带的 Mcp-Session-Id；返回 false 时已写好错误响应
+func (st *sessionStore) lookup(w http.ResponseWriter, r *http.Request) (*session, bool) {
+	sid := r.Header.Get("Mcp-Session-Id")
+	if sid == "" {
+		if sessionRequired {
+			http.Error(w, "Bad Request: missing Mcp-Session-Id", http.StatusBadRequest)
+			return nil, false
+		}
+		return nil, true
+	}
+	se, ok := st.get(sid)
+	// 会话绑定到创建它的认证身份，其他身份拿到同一个 id 也视为不存在
+	if !ok || se.Client != clientFrom(r.Context()) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return nil, false
+	}
+	return se, true
+}
+
+type sessionKey struct{}
+
+func withSession(ctx context.Context, se *session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, se)
+}
+func sessionFrom(ctx context.Context) *session {
+	se, _ := ctx.Value(sessionKey{}).(*session)
+	return se
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sseBackend 实现旧版 HTTP+SSE 传输（"type": "sse"）：
+// GET 建立事件流，收到 endpoint 事件后向该地址 POST 消息，响应经事件流返回。
+// 事件流断开时自动重连，并在新连接上重新握手
+type sseBackend struct {
+	name    string
+	url     string
+	headers map[string]string
+	stream  *http.Client
+	client  *http.Client
+	slots   callSlots
+
+	seq     int64
+	pending map[string]chan map[string]any
+	pm      sync.Mutex
+
+	mu       sync.Mutex
+	endpoint string
+	epReady  chan struct{}
+	inited   bool
+	started  bool
+	caps     map[string]any
+	notifyHub
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	cancel    context.CancelFunc
+}
+
+func newSSEBackend(name string, sp SrvSpec) (*sseBackend, error) {
+	if sp.URL == "" {
+		return nil, fmt.Errorf("%s: sse missing url", name)
+	}
+	return &sseBackend{
+		name:    name,
+		url:     sp.URL,
+		headers: sp.Headers,
+		stream:  &http.Client{},
+		client:  &http.Client{Timeout: timeout},
+		slots:   newCallSlots(sp.MaxConcurrent),
+		pending: make(map[string]chan map[string]any),
+		epReady: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}, nil
+}
+func (s *sseBackend) Name() string { return s.name }
+func (s *sseBackend) Initialize(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.started {
+		s.started = true
+		lctx, cancel := context.WithCancel(context.Background())
+		s.cancel = cancel
+		go s.loop(lctx)
+	}
+	s.mu.Unlock()
+	if err := s.handshake(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.inited = true
+	s.mu.Unlock()
+	return nil
+}
+func (s *sseBackend) handshake(ctx context.Context) error {
+	params := map[string]any{
+		"protocolVersion": "2024-11-05",
+		"capabilities": map[string]any{
+			"tools": map[string]any{},
+		},
+		"clientInfo": map[string]any{
+			"name":    "mcp-bridge",
+			"version": "0.3.0",
+		},
+	}
+	res, err := s.rpc(ctx, "initialize", params)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.caps, _ = res["capabilities"].(map[string]any)
+	s.mu.Unlock()
+	return s.send(ctx, map[string]any{"jsonrpc": "2.0", "method": "notifications/initialized"})
+}
+func (s *sseBackend) Capabilities() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.caps
+}
+func (s *sseBackend) Request(ctx context.Context, method string, params map[string]any) (map[string]any, error) {
+	return s.rpc(ctx, method, params)
+}
+func (s *sseBackend) ListTools(ctx context.Context) ([]ToolItem, error) {
+	res, err := s.rpc(ctx, "tools/list", map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+	b, _ := json.Marshal(res["tools"])
+	var out []ToolItem
+	_ = json.Unmarshal(b, &out)
+	return out, nil
+}
+func (s *sseBackend) CallTool(ctx context.Context, tool string, args map[string]any) (map[string]any, error) {
+	return s.rpc(ctx, "tools/call", callParams(ctx, tool, args))
+}
+func (s *sseBackend) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+	return nil
+}
+func (s *sseBackend) nextID() string {
+	s.pm.Lock()
+	defer s.pm.Unlock()
+	s.seq++
+	return strconv.FormatInt(s.seq, 10)
+}
+
+// loop 维持事件流，断开后按指数退避重连
+func (s *sseBackend) loop(ctx context.Context) {
+	delay := restartBackoff
+	for {
+		connected, err := s.connect(ctx)
+		select {
+		case <-ctx.Done():
+			s.failPending(errors.New("backend closed"))
+			return
+		default:
+		}
+		s.mu.Lock()
+		s.endpoint = ""
+		s.epReady = make(chan struct{})
+		s.mu.Unlock()
+		s.failPending(errors.New("sse stream dropped"))
+		if connected {
+			delay = restartBackoff
+		}
+		log.Printf("[%s] sse stream lost: %v, reconnecting in %s", s.name, err, delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > restartBackoffMax {
+			delay = restartBackoffMax
+		}
+	}
+}
+
+// connect 建立一次事件流并阻塞读取，connected 表示是否收到过 endpoint 事件
+func (s *sseBackend) connect(ctx context.Context) (connected bool, err error) {
+	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return false, err
+	}
+	rq.Header.Set("Accept", "text/event-stream")
+	for k, v := range s.headers {
+		rq.Header.Set(k, v)
+	}
+	resp, err := s.stream.Do(rq)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return false, fmt.Errorf("http %d: %s", resp.StatusCode, string(b))
+	}
+	err = readSSE(resp.Body, func(event, data string) bool {
+		switch event {
+		case "endpoint":
+			ep, perr := s.resolve(data)
+			if perr != nil {
+				log.Printf("[%s] bad endpoint %q: %v", s.name, data, perr)
+				return true
+			}
+			s.mu.Lock()
+			// 同一条流上的后续 endpoint 事件只更新地址，epReady 每次连接只关闭一次
+			first := s.endpoint == ""
+			s.endpoint = ep
+			if first {
+				close(s.epReady)
+			}
+			reinit := s.inited
+			s.mu.Unlock()
+			connected = true
+			if first && reinit {
+				go s.rehandshake()
+			}
+		case "", "message":
+			var m map[string]any
+			if json.Unmarshal([]byte(data), &m) != nil {
+				log.Printf("[%s] bad json on sse stream", s.name)
+				return true
+			}
+			s.deliver(m)
+		}
+		return true
+	})
+	if err == nil {
+		err = io.EOF
+	}
+	return connected, err
+}
+func (s *sseBackend) rehandshake() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := s.handshake(ctx); err != nil {
+		log.Printf("[%s] re-initialize after reconnect failed: %v", s.name, err)
+	}
+}
+func (s *sseBackend) resolve(ep string) (string, error) {
+	ep = strings.TrimSpace(ep)
+	// 部分实现把 endpoint 当 JSON 字符串发送
+	var quoted string
+	if json.Unmarshal([]byte(ep), &quoted) == nil {
+		ep = quoted
+	}
+	base, err := url.Parse(s.url)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(ep)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+func (s *sseBackend) deliver(m map[string]any) {
+	if m["method"] != nil && m["id"] == nil {
+		s.emit(m)
+		return
+	}
+	if m["id"] == nil || (m["result"] == nil && m["error"] == nil) {
+		return
+	}
+	id := fmt.Sprint(m["id"])
+	s.pm.Lock()
+	ch := s.pending[id]
+	if ch != nil {
+		ch <- m
+		delete(s.pending, id)
+	}
+	s.pm.Unlock()
+}
+func (s *sseBackend) failPending(err error) {
+	s.pm.Lock()
+	defer s.pm.Unlock()
+	for id, ch := range s.pending {
+		ch <- map[string]any{"id": id, "error": map[string]any{"message": err.Error()}}
+		delete(s.pending, id)
+	}
+}
+
+// waitEndpoint 等待当前连接的 endpoint 就绪
+func (s *sseBackend) waitEndpoint(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	ready := s.epReady
+	s.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-s.closed:
+		return "", errors.New("backend closed")
+	case <-ready:
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.endpoint == "" {
+		return "", errors.New("sse stream dropped")
+	}
+	return s.endpoint, nil
+}
+func (s *sseBackend) send(ctx context.Context, msg map[string]any) error {
+	ep, err := s.waitEndpoint(ctx)
+	if err != nil {
+		return err
+	}
+	body, _ := json.Marshal(msg)
+	rq, err := http.NewRequestWithContext(ctx, http.MethodPost, ep, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	rq.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		rq.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(rq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("http %d: %s", resp.StatusCode, string(b))
+	}
+	// 个别实现直接在 POST 响应里返回结果
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var m map[string]any
+		if json.NewDecoder(resp.Body).Decode(&m) == nil {
+			s.deliver(m)
+		}
+	}
+	return nil
+}
+func (s *sseBackend) rpc(ctx context.Context, method string, params map[string]any) (map[string]any, error) {
+	if err := s.slots.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.slots.release()
+	id := s.nextID()
+	ch := make(chan map[string]any, 1)
+	s.pm.Lock()
+	s.pending[id] = ch
+	s.pm.Unlock()
+	defer func() {
+		s.pm.Lock()
+		delete(s.pending, id)
+		s.pm.Unlock()
+	}()
+	msg := map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+	if err := s.send(ctx, msg); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		if method != "initialize" {
+			go s.cancelRemote(id, ctx.Err())
+		}
+		return nil, ctx.Err()
+	case <-s.closed:
+		return nil, errors.New("backend closed")
+	case resp := <-ch:
+		return rpcResult(resp)
+	}
+}
+
+// cancelRemote 通知后端放弃已经不再等待的请求
+func (s *sseBackend) cancelRemote(id string, reason error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = s.send(ctx, map[string]any{"jsonrpc": "2.0", "method": "notifications/cancelled", "params": cancelledParams(id, reason)})
+}
@@ -15,16 +15,49 @@ logging.basicConfig(level=logging.DEBUG, stream=sys.stderr,
 
 MCP_BRIDGE_URL = "http://localhost:7011/mcp"
 
+# Mcp-Session-Id returned by initialize, replayed on every later request
+session_id = None
+# The client's initialize request, replayed when the bridge has expired the session
+init_request = None
+
+def post(data):
+    headers = {"Accept": "application/json"}
+    if session_id:
+        headers["Mcp-Session-Id"] = session_id
+    return requests.post(MCP_BRIDGE_URL, json=data, headers=headers, timeout=30)
+
+def handshake():
+    """Open a new bridge session with the saved initialize request"""
+    global session_id
+    session_id = None
+    response = post(init_request)
+    session_id = response.headers.get("Mcp-Session-Id")
+    post({"jsonrpc": "2.0", "method": "notifications/initialized"})
+    return response
+
 def send_request(data):
-    """Send request to mcp-bridge HTTP server"""
+    """Send request to mcp-bridge HTTP server; returns None for notifications"""
+    global init_request
     try:
         logging.debug(f"Sending request: {data}")
-        response = requests.post(MCP_BRIDGE_URL, json=data, timeout=30)
+        if data.get("method") == "initialize":
+            init_request = data
+            response = handshake()
+        else:
+            response = post(data)
+            if response.status_code == 404 and init_request is not None:
+                logging.info("Session expired, re-initializing")
+                handshake()
+                response = post(data)
+        if data.get("id") is None:
+            return None
         result = response.json()
         logging.debug(f"Received response: {result}")
         return result
     except Exception as e:
         logging.error(f"Error sending request: {e}")
+        if data.get("id") is None:
+            return None
         return {
             "jsonrpc": "2.0",
             "id": data.get("id"),
@@ -47,6 +80,8 @@ def main():
             try:
                 request = json.loads(line)
                 response = send_request(request)
+                if response is None:
+                    continue
                 output = json.dumps(response)
                 print(output)
                 sys.stdout.flush()
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	restartBackoff    = getenvDur("RESTART_BACKOFF", time.Second)
+	restartBackoffMax = getenvDur("RESTART_BACKOFF_MAX", time.Minute)
+)
+
+const (
+	stateStarting = "starting"
+	stateReady    = "ready"
+	stateFailed   = "failed"
+	stateRetrying = "retrying"
+	// stateDisabled 是被管理接口停用的后端
+	stateDisabled = "disabled"
+)
+
+// exitWatcher 由会意外退出的后端实现（目前是 stdio 子进程）
+type exitWatcher interface {
+	Done() <-chan struct{}
+	ExitStatus() string
+	PID() int
+}
+
+type backendStatus struct {
+	Name       string `json:"name"`
+	Transport  string `json:"transport"`
+	PID        int    `json:"pid,omitempty"`
+	State      string `json:"state"`
+	Tools      int    `json:"tools"`
+	LastError  string `json:"lastError,omitempty"`
+	Restarts   int    `json:"restarts"`
+	LastExit   string `json:"lastExit,omitempty"`
+	LastExitAt int64  `json:"lastExitAt,omitempty"`
+	ReadySince int64  `json:"readySince,omitempty"`
+	Required   bool   `json:"required,omitempty"`
+	LastPing   int64  `json:"lastPing,omitempty"`
+	PingError  string `json:"pingError,omitempty"`
+	Breaker    string `json:"breaker,omitempty"`
+}
+
+type supervised struct {
+	raw      string
+	spec     SrvSpec
+	stop     chan struct{}
+	stopOnce sync.Once
+	status   backendStatus
+	guard    *backendGuard
+}
+
+func (sv *supervised) halt() { sv.stopOnce.Do(func() { close(sv.stop) }) }
+func (sv *supervised) stopped() bool {
+	select {
+	case <-sv.stop:
+		return true
+	default:
+		return false
+	}
+}
+func (a *Aggregator) setState(sv *supervised, state string, err error) {
+	a.mu.Lock()
+	sv.status.State = state
+	if err != nil {
+		sv.status.LastError = secrets.scrub(err.Error())
+	}
+	a.mu.Unlock()
+}
+
+// start 登记新的 supervisor 并在后台拉起后端，立即返回。
+// 同名的旧 supervisor 会被停掉，但旧后端继续服务直到新后端就绪后被替换
+func (a *Aggregator) start(raw, name string, sp SrvSpec) *supervised {
+	sv := &supervised{raw: raw, spec: sp, stop: make(chan struct{}), status: backendStatus{Transport: backendKind(sp), State: stateStarting}, guard: newBackendGuard(name, sp)}
+	a.mu.Lock()
+	prev := a.sup[name]
+	a.sup[name] = sv
+	a.mu.Unlock()
+	a.setCaches(name, sp.Cache)
+	if prev != nil {
+		prev.halt()
+	}
+	go a.run(name, sv)
+	return sv
+}
+
+// run 带重试地完成首次启动，成功后转入 supervise
+func (a *Aggregator) run(name string, sv *supervised) {
+	for attempt := 1; ; attempt++ {
+		bk, cat, err := a.launch(name, sv.spec)
+		if err == nil {
+			old, ok := a.install(name, sv, bk, cat)
+			if !ok {
+				_ = bk.Close()
+				return
+			}
+			if old != nil {
+				go closeAfterDrain(old)
+			}
+			a.setState(sv, stateReady, nil)
+			log.Printf("[%s] ready, tools: %d, resources: %d, prompts: %d", sv.raw, len(cat.tools), len(cat.resources), len(cat.prompts))
+			a.supervise(name, sv, bk)
+			return
+		}
+		if sv.stopped() {
+			return
+		}
+		if attempt >= initRetry {
+			log.Printf("[%s] start failed after %d attempts: %v", sv.raw, attempt, err)
+			a.setState(sv, stateFailed, err)
+			a.evict(name, sv)
+			return
+		}
+		log.Printf("[%s] start attempt %d failed: %v", sv.raw, attempt, err)
+		a.setState(sv, stateRetrying, err)
+		select {
+		case <-sv.stop:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// evict 在 sv 启动失败时摘除该名字下残留的旧后端，使运行状态与配置一致
+func (a *Aggregator) evict(name string, sv *supervised) {
+	a.mu.Lock()
+	if a.sup[name] != sv {
+		a.mu.Unlock()
+		return
+	}
+	old, oldCat := a.backends[name], a.catalogs[name]
+	delete(a.backends, name)
+	a.dropTools(name)
+	a.mu.Unlock()
+	if oldCat != nil {
+		a.catalogChanged(oldCat)
+	}
+	if old != nil {
+		go closeAfterDrain(old)
+	}
+}
+
+// supervise 监视后端进程退出，按指数退避重新拉起并原子替换到 Aggregator
+func (a *Aggregator) supervise(name string, sv *supervised, bk Backend) {
+	delay := restartBackoff
+	started := time.Now()
+	for {
+		w, ok := bk.(exitWatcher)
+		if !ok {
+			return
+		}
+		select {
+		case <-sv.stop:
+			return
+		case <-w.Done():
+		}
+		if sv.stopped() {
+			return
+		}
+		_ = bk.Close()
+		status := w.ExitStatus()
+		a.mu.Lock()
+		sv.status.State = stateRetrying
+		sv.status.LastExit = status
+		sv.status.LastExitAt = time.Now().Unix()
+		a.mu.Unlock()
+		log.Printf("[%s] backend exited: %s", sv.raw, status)
+		if time.Since(started) > restartBackoffMax {
+			delay = restartBackoff
+		}
+		for {
+			log.Printf("[%s] restarting in %s", sv.raw, delay)
+			select {
+			case <-sv.stop:
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > restartBackoffMax {
+				delay = restartBackoffMax
+			}
+			nb, cat, err := a.launch(name, sv.spec)
+			if err != nil {
+				log.Printf("[%s] restart failed: %v", sv.raw, err)
+				a.setState(sv, stateRetrying, err)
+				continue
+			}
+			if _, ok := a.install(name, sv, nb, cat); !ok {
+				_ = nb.Close()
+				return
+			}
+			a.mu.Lock()
+			sv.status.Restarts++
+			sv.status.State = stateReady
+			a.mu.Unlock()
+			log.Printf("[%s] restarted, tools: %d", sv.raw, len(cat.tools))
+			bk, started = nb, time.Now()
+			break
+		}
+	}
+}
+
+// WaitReady 等到所有后端都就绪或放弃重试；有后端失败或 ctx 结束时返回说明哪些后端没有就绪的错误
+func (a *Aggregator) WaitReady(ctx context.Context) error {
+	t := time.NewTicker(100 * time.Millisecond)
+	defer t.Stop()
+	for {
+		var pending, failed []string
+		for _, st := range a.Status() {
+			switch st.State {
+			case stateReady:
+			case stateFailed:
+				failed = append(failed, fmt.Sprintf("%s (%s)", st.Name, st.LastError))
+			default:
+				pending = append(pending, st.Name)
+			}
+		}
+		if len(pending) == 0 {
+			if len(failed) > 0 {
+				return fmt.Errorf("backends failed: %s", strings.Join(failed, ", "))
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("backends not ready: %s", strings.Join(append(pending, failed...), ", "))
+		case <-t.C:
+		}
+	}
+}
+
+// Status 返回各后端（包括被管理接口停用的）的状态、重启次数与最近一次退出状态
+func (a *Aggregator) Status() []backendStatus {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := make([]backendStatus, 0, len(a.sup)+len(a.parked))
+	for name, sv := range a.sup {
+		st := sv.status
+		st.Name = name
+		st.Breaker = sv.guard.breakerState()
+		st.Required = sv.spec.Required
+		if w, ok := a.backends[name].(exitWatcher); ok {
+			st.PID = w.PID()
+		}
+		out = append(out, st)
+	}
+	for name, sv := range a.parked {
+		st := sv.status
+		st.Name, st.State, st.Tools, st.ReadySince = name, stateDisabled, 0, 0
+		st.Required = sv.spec.Required
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -118,6 +118,9 @@ def main():
             request = read_message()
             if request is None:
                 break
+            # Notifications (initialized, cancelled) get no reply
+            if request.get('id') is None:
+                continue
             response = mcp.handle_request(request)
             send_message(response)
         except Exception as e: